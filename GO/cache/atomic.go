@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrNotInteger is returned by Incr and Decr when the stored value is not an integer.
+var ErrNotInteger = errors.New("cache: value is not an integer")
+
+// ErrOverflow is returned by Incr and Decr when the result does not fit the stored integer type.
+var ErrOverflow = errors.New("cache: integer overflow")
+
+// ErrVersionMismatch is returned by SetIfVersion when the entry was modified since it was read.
+var ErrVersionMismatch = errors.New("cache: version mismatch")
+
+// GetWithVersion returns the value stored under the key together with its version.
+// The version changes whenever the entry is written and can be passed to SetIfVersion.
+func (c *TypedCache[K, V]) GetWithVersion(key K) (V, uint64, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) {
+		c.countLookup(false)
+		var zero V
+		return zero, 0, false
+	}
+	c.countLookup(true)
+	return c.detach(e.value), e.version, true
+}
+
+// SetIfVersion stores the value only if the entry still has the given version.
+// It returns ErrNotFound if the key is missing and ErrVersionMismatch if the entry has changed.
+func (c *TypedCache[K, V]) SetIfVersion(key K, value V, ttl time.Duration, version uint64) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) {
+		return ErrNotFound
+	}
+	if e.version != version {
+		return ErrVersionMismatch
+	}
+	c.set(key, value, c.deadlineFor(key, ttl))
+	return nil
+}
+
+// SetIfAbsent stores the value only if the key is not already in the cache
+// and reports whether it was stored.
+func (c *TypedCache[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if e, ok := c.find(key); ok && !e.expired(c.clock.Now()) {
+		return false
+	}
+	c.set(key, value, c.deadline(ttl))
+	return true
+}
+
+// Replace stores the value only if the key is already in the cache
+// and reports whether it was stored.
+func (c *TypedCache[K, V]) Replace(key K, value V, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) {
+		return false
+	}
+	c.set(key, value, c.deadlineFor(key, ttl))
+	return true
+}
+
+// CompareAndSwap stores new under the key only if the current value equals old,
+// and reports whether it did. The entry keeps its TTL.
+// It panics if the stored values are not comparable.
+func (c *TypedCache[K, V]) CompareAndSwap(key K, old, new V) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) || any(e.value) != any(old) {
+		return false
+	}
+	c.set(key, new, e.expiresAt)
+	return true
+}
+
+// Update atomically replaces the entry for the key with the result of fn, which is called
+// with the current value and whether it exists while the cache is locked. If fn returns
+// keep == false the entry is deleted. An existing entry keeps its TTL and a new one gets the
+// default TTL. Update returns the stored value and whether an entry exists afterwards.
+// fn must not call back into the cache.
+func (c *TypedCache[K, V]) Update(key K, fn func(old V, ok bool) (new V, keep bool)) (V, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	var old V
+	e, ok := c.find(key)
+	if ok && e.expired(c.clock.Now()) {
+		ok = false
+	}
+	if ok {
+		old = e.value
+	}
+
+	next, keep := fn(old, ok)
+	if !keep {
+		if e != nil {
+			c.removeEntry(e, ReasonDeleted)
+		}
+		var zero V
+		return zero, false
+	}
+
+	c.set(key, next, c.deadlineFor(key, KeepTTL))
+	return next, true
+}
+
+// Incr atomically adds delta to the integer stored under the key and returns the new value.
+// A missing key is treated as zero and created with the default TTL; an existing entry keeps
+// its TTL. The stored value keeps its integer type; a new entry in an interface{} cache holds
+// an int64.
+func (c *TypedCache[K, V]) Incr(key K, delta int64) (int64, error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	var current V
+	expiresAt := c.deadline(DefaultExpiration)
+	if e, ok := c.find(key); ok && !e.expired(c.clock.Now()) {
+		current = e.value
+		expiresAt = e.expiresAt
+	}
+
+	next, n, err := addInt(current, delta)
+	if err != nil {
+		return 0, err
+	}
+	c.set(key, next, expiresAt)
+	return n, nil
+}
+
+// Decr atomically subtracts delta from the integer stored under the key and returns the
+// new value. It behaves like Incr with a negated delta.
+func (c *TypedCache[K, V]) Decr(key K, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+	return c.Incr(key, -delta)
+}
+
+// addInt adds delta to an integer value of any built-in integer type, preserving the type.
+// A nil interface value counts as int64(0). Unsigned values are limited to math.MaxInt64
+// so that the result can always be reported as an int64.
+func addInt[V any](v V, delta int64) (V, int64, error) {
+	var (
+		n      int64
+		err    error
+		result any
+	)
+
+	switch x := any(v).(type) {
+	case nil:
+		n, result = delta, delta
+	case int:
+		n, err = addSigned(int64(x), delta, math.MinInt, math.MaxInt)
+		result = int(n)
+	case int8:
+		n, err = addSigned(int64(x), delta, math.MinInt8, math.MaxInt8)
+		result = int8(n)
+	case int16:
+		n, err = addSigned(int64(x), delta, math.MinInt16, math.MaxInt16)
+		result = int16(n)
+	case int32:
+		n, err = addSigned(int64(x), delta, math.MinInt32, math.MaxInt32)
+		result = int32(n)
+	case int64:
+		n, err = addSigned(x, delta, math.MinInt64, math.MaxInt64)
+		result = n
+	case uint:
+		n, err = addUnsigned(uint64(x), delta, math.MaxInt64)
+		result = uint(n)
+	case uint8:
+		n, err = addUnsigned(uint64(x), delta, math.MaxUint8)
+		result = uint8(n)
+	case uint16:
+		n, err = addUnsigned(uint64(x), delta, math.MaxUint16)
+		result = uint16(n)
+	case uint32:
+		n, err = addUnsigned(uint64(x), delta, math.MaxUint32)
+		result = uint32(n)
+	case uint64:
+		n, err = addUnsigned(x, delta, math.MaxInt64)
+		result = uint64(n)
+	default:
+		return v, 0, ErrNotInteger
+	}
+	if err != nil {
+		return v, 0, err
+	}
+
+	next, ok := result.(V)
+	if !ok {
+		return v, 0, ErrNotInteger
+	}
+	return next, n, nil
+}
+
+// addSigned adds delta to x and checks the result against [min, max].
+func addSigned(x, delta, min, max int64) (int64, error) {
+	if (delta > 0 && x > max-delta) || (delta < 0 && x < min-delta) {
+		return 0, ErrOverflow
+	}
+	return x + delta, nil
+}
+
+// addUnsigned adds delta to x and checks the result against [0, max], where max <= math.MaxInt64.
+func addUnsigned(x uint64, delta int64, max int64) (int64, error) {
+	if x > uint64(max) {
+		return 0, ErrOverflow
+	}
+	return addSigned(int64(x), delta, 0, max)
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncr(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	c.Set("int", 1)
+	c.Set("uint8", uint8(254))
+	c.Set("max", int64(math.MaxInt64))
+	c.Set("text", "1")
+
+	testCases := []struct {
+		name    string
+		key     string
+		delta   int64
+		want    int64
+		wantErr error
+	}{
+		{name: "missing key starts at zero", key: "new", delta: 5, want: 5},
+		{name: "int keeps counting", key: "int", delta: 2, want: 3},
+		{name: "negative delta", key: "int", delta: -4, want: -1},
+		{name: "uint8 up to its limit", key: "uint8", delta: 1, want: 255},
+		{name: "uint8 overflow", key: "uint8", delta: 1, wantErr: ErrOverflow},
+		{name: "int64 overflow", key: "max", delta: 1, wantErr: ErrOverflow},
+		{name: "string is not an integer", key: "text", delta: 1, wantErr: ErrNotInteger},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.Incr(tc.key, tc.delta)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Incr(%q, %d) error = %v, want %v", tc.key, tc.delta, err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Errorf("Incr(%q, %d) = %d, want %d", tc.key, tc.delta, got, tc.want)
+			}
+		})
+	}
+
+	if v, _ := c.Get("int"); v != -1 {
+		t.Errorf("Get(int) = %#v, want int(-1)", v)
+	}
+	if v, _ := c.Get("new"); v != int64(5) {
+		t.Errorf("Get(new) = %#v, want int64(5)", v)
+	}
+}
+
+func TestConditionalSets(t *testing.T) {
+	c := NewTyped[string, string]()
+	defer c.Close()
+
+	if !c.SetIfAbsent("k", "a", NoExpiration) {
+		t.Errorf("SetIfAbsent(k) on missing key = false, want true")
+	}
+	if c.SetIfAbsent("k", "b", NoExpiration) {
+		t.Errorf("SetIfAbsent(k) on existing key = true, want false")
+	}
+	if c.Replace("missing", "x", NoExpiration) {
+		t.Errorf("Replace(missing) = true, want false")
+	}
+
+	_, version, _ := c.GetWithVersion("k")
+	if !c.Replace("k", "c", KeepTTL) {
+		t.Errorf("Replace(k) = false, want true")
+	}
+	if err := c.SetIfVersion("k", "d", KeepTTL, version); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("SetIfVersion() with stale version error = %v, want ErrVersionMismatch", err)
+	}
+
+	v, version, _ := c.GetWithVersion("k")
+	if err := c.SetIfVersion("k", "d", KeepTTL, version); err != nil {
+		t.Errorf("SetIfVersion() with current version error = %v", err)
+	}
+	if err := c.SetIfVersion("missing", "d", KeepTTL, version); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetIfVersion() on missing key error = %v, want ErrNotFound", err)
+	}
+	if v != "c" {
+		t.Errorf("GetWithVersion(k) = %q, want c", v)
+	}
+
+	c.Clear()
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() after Clear = %d, want 0", n)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	c := NewTyped[string, string]()
+	defer c.Close()
+	c.Set("state", "idle")
+
+	if c.CompareAndSwap("state", "running", "done") {
+		t.Errorf("CompareAndSwap(running -> done) = true, want false")
+	}
+	if !c.CompareAndSwap("state", "idle", "running") {
+		t.Errorf("CompareAndSwap(idle -> running) = false, want true")
+	}
+	if c.CompareAndSwap("missing", "", "x") {
+		t.Errorf("CompareAndSwap on missing key = true, want false")
+	}
+	if v, _ := c.Get("state"); v != "running" {
+		t.Errorf("Get(state) = %q, want running", v)
+	}
+}
+
+func TestUpdateAndCountersAreAtomic(t *testing.T) {
+	c := NewTyped[string, int](WithDefaultTTL(time.Hour))
+	defer c.Close()
+
+	const workers, perWorker = 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				c.Update("updated", func(old int, ok bool) (int, bool) { return old + 1, true })
+				c.Incr("incr", 2)
+				c.Decr("incr", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range []string{"updated", "incr"} {
+		if v, _ := c.Get(key); v != workers*perWorker {
+			t.Errorf("Get(%q) = %d, want %d", key, v, workers*perWorker)
+		}
+	}
+
+	if v, ok := c.Update("updated", func(old int, ok bool) (int, bool) { return 0, false }); ok || v != 0 {
+		t.Errorf("Update() deleting = %v, %v, want 0, false", v, ok)
+	}
+	if _, ok := c.Get("updated"); ok {
+		t.Errorf("Get(updated) hit after Update returned keep == false")
+	}
+}
@@ -1,33 +1,26 @@
 package cache
 
-import "sync"
+import "time"
 
-type Cache struct {
-	mu    sync.RWMutex
-	store map[string]interface{}
-}
-
-func New() *Cache {
-	return &Cache{
-		store: make(map[string]interface{}),
-	}
-}
+// Expiration values accepted by SetWithTTL and the other methods that take a TTL.
+const (
+	// NoExpiration marks an entry that never expires.
+	NoExpiration time.Duration = -1
+	// DefaultExpiration applies the cache's default TTL configured with WithDefaultTTL.
+	DefaultExpiration time.Duration = 0
+	// KeepTTL keeps the expiration of the entry being overwritten. A new entry gets the default TTL.
+	KeepTTL time.Duration = -2
+)
 
-func (c *Cache) Set(key string, value interface{}) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.store[key] = value
-}
-
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	val, ok := c.store[key]
-	return val, ok
+// Cache is the string-keyed cache holding values of any type. It is a thin wrapper around
+// TypedCache[string, interface{}] kept for compatibility; new code should prefer NewTyped
+// so that values do not need type assertions.
+type Cache struct {
+	*TypedCache[string, interface{}]
 }
 
-func (c *Cache) Delete(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	delete(c.store, key)
+// New creates an empty cache configured by the given options.
+// If a cleanup interval is configured, the caller should call Close() to stop the janitor.
+func New(opts ...Option) *Cache {
+	return &Cache{TypedCache: NewTyped[string, interface{}](opts...)}
 }
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced Clock for deterministic expiration tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestSetGetDelete(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a) after Delete found a value")
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	clock := newFakeClock()
+	c := New(WithClock(clock), WithDefaultTTL(time.Minute))
+	defer c.Close()
+
+	c.Set("default", "v")
+	c.SetWithTTL("short", "v", time.Second)
+	c.SetWithTTL("forever", "v", NoExpiration)
+
+	testCases := []struct {
+		name    string
+		advance time.Duration
+		key     string
+		wantHit bool
+	}{
+		{name: "short entry alive before ttl", advance: 500 * time.Millisecond, key: "short", wantHit: true},
+		{name: "short entry expires at ttl", advance: 500 * time.Millisecond, key: "short", wantHit: false},
+		{name: "default ttl entry still alive", advance: 0, key: "default", wantHit: true},
+		{name: "default ttl entry expires", advance: time.Minute, key: "default", wantHit: false},
+		{name: "no expiration entry survives", advance: 24 * time.Hour, key: "forever", wantHit: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock.Advance(tc.advance)
+			if _, ok := c.Get(tc.key); ok != tc.wantHit {
+				t.Errorf("Get(%q) hit = %v, want %v", tc.key, ok, tc.wantHit)
+			}
+		})
+	}
+
+	if removed := c.DeleteExpired(); removed != 2 {
+		t.Errorf("DeleteExpired() = %d, want 2", removed)
+	}
+}
+
+func TestJanitorSweepsAndStops(t *testing.T) {
+	c := New(WithCleanupInterval(time.Millisecond))
+	c.SetWithTTL("k", "v", time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.RLock()
+		n := len(c.store)
+		c.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not remove the expired entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Close()
+	c.Close()
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := New(WithMaxEntries(2))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) hit, want least recently used entry evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Get(%q) missed, want hit", key)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+	if got := c.Evictions(); got != 1 {
+		t.Errorf("Evictions() = %d, want 1", got)
+	}
+
+	c.Set("a", 10)
+	if got := c.Evictions(); got != 1 {
+		t.Errorf("Evictions() after overwrite = %d, want 1", got)
+	}
+}
+
+func TestTypedCache(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	c := NewTyped[int, user](WithMaxEntries(10))
+	defer c.Close()
+
+	c.Set(1, user{ID: 1, Name: "Ann"})
+
+	got, ok := c.Get(1)
+	if !ok || got.Name != "Ann" {
+		t.Errorf("Get(1) = %v, %v, want {1 Ann}, true", got, ok)
+	}
+
+	missing, ok := c.Get(2)
+	if ok || missing != (user{}) {
+		t.Errorf("Get(2) = %v, %v, want zero value, false", missing, ok)
+	}
+}
+
+func TestTypedGetDoesNotAllocate(t *testing.T) {
+	c := NewTyped[string, int]()
+	defer c.Close()
+	c.Set("a", 1<<20)
+
+	if allocs := testing.AllocsPerRun(100, func() { c.Get("a") }); allocs != 0 {
+		t.Errorf("Get() allocates %v times, want 0", allocs)
+	}
+}
+
+func TestCostEviction(t *testing.T) {
+	c := NewTyped[string, []byte](
+		WithMaxCost(10),
+		WithCostFunc(func(key string, value []byte) int64 { return int64(len(value)) }),
+	)
+	defer c.Close()
+
+	c.Set("a", make([]byte, 4))
+	c.Set("b", make([]byte, 4))
+	c.Get("a")
+	c.Set("c", make([]byte, 4))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) hit, want least recently used entry evicted to fit the budget")
+	}
+	if got := c.Stats().Cost; got != 8 {
+		t.Errorf("Stats().Cost = %d, want 8", got)
+	}
+
+	c.SetWith("a", []byte("x"), WithCost(6))
+	if got := c.Stats().Cost; got != 10 {
+		t.Errorf("Stats().Cost after WithCost = %d, want 10", got)
+	}
+	c.Update("a", func(old []byte, ok bool) ([]byte, bool) { return []byte("xy"), true })
+	if got := c.Stats().Cost; got != 6 {
+		t.Errorf("Stats().Cost after Update = %d, want the cost function's 6", got)
+	}
+
+	c.Set("c", make([]byte, 11))
+	if _, ok := c.Get("c"); ok {
+		t.Errorf("Get(c) hit after storing a value over the whole budget, want it dropped")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("Get(a) missed, want other entries kept when an oversized value is dropped")
+	}
+	if got := c.Stats(); got.Cost != 2 || got.Size != 1 {
+		t.Errorf("Stats() = %+v, want cost 2 and size 1", got)
+	}
+}
+
+func TestCostFuncTypeMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewTyped with a mismatched cost function did not panic")
+		}
+	}()
+	NewTyped[string, int](WithCostFunc(func(key string, value string) int64 { return 1 }))
+}
@@ -0,0 +1,109 @@
+// Command cache-server serves an in-memory cache over TCP using the Redis protocol
+// and, optionally, the memcached text protocol and the HTTP/JSON admin API. It can also
+// replicate its contents to followers or follow another cache-server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+	"github.com/sKrasiuk/PubRep/GO/cache/httpapi"
+	"github.com/sKrasiuk/PubRep/GO/cache/memcache"
+	"github.com/sKrasiuk/PubRep/GO/cache/replication"
+	"github.com/sKrasiuk/PubRep/GO/cache/resp"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6379", "TCP address to serve the Redis protocol on")
+	memcacheAddr := flag.String("memcache-addr", "", "TCP address to serve the memcached protocol on, empty to disable")
+	httpAddr := flag.String("http-addr", "", "TCP address to serve the HTTP/JSON API on, empty to disable")
+	httpToken := flag.String("http-token", "", "bearer token required by the HTTP/JSON API")
+	maxEntries := flag.Int("max-entries", 0, "maximum number of entries, 0 for unbounded")
+	cleanup := flag.Duration("cleanup-interval", time.Minute, "how often expired entries are swept")
+	snapshot := flag.String("snapshot", "", "snapshot file restored on start and written periodically")
+	snapshotInterval := flag.Duration("snapshot-interval", 5*time.Minute, "how often the snapshot is written")
+	replicateAddr := flag.String("replicate-addr", "", "TCP address to serve followers on, empty to disable")
+	follow := flag.String("follow", "", "replication address of a leader to follow, empty to disable")
+	flag.Parse()
+
+	opts := []cache.Option{
+		cache.WithMaxEntries(*maxEntries),
+		cache.WithCleanupInterval(*cleanup),
+	}
+	if *snapshot != "" {
+		opts = append(opts, cache.WithSnapshot(*snapshot, *snapshotInterval))
+	}
+	c := cache.New(opts...)
+
+	if *snapshot != "" {
+		if err := c.LoadFile(*snapshot); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("restoring snapshot: %v", err)
+		}
+	}
+
+	srv := resp.NewServer(c)
+	mcSrv := memcache.NewServer(c)
+	httpSrv := &http.Server{Addr: *httpAddr, Handler: httpapi.NewHandler(c, httpapi.WithBearerToken(*httpToken))}
+	logErrors := replication.WithErrorHandler(func(err error) { log.Printf("replication: %v", err) })
+	leader := replication.NewLeader(c.TypedCache, logErrors)
+	ctx, stop := context.WithCancel(context.Background())
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		stop()
+		leader.Close()
+		httpSrv.Close()
+		mcSrv.Close()
+		srv.Close()
+	}()
+
+	if *replicateAddr != "" {
+		go func() {
+			log.Printf("cache-server serving followers on %s", *replicateAddr)
+			if err := leader.ListenAndServe(*replicateAddr); err != nil && !errors.Is(err, replication.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	if *follow != "" {
+		go func() {
+			log.Printf("cache-server following %s", *follow)
+			replication.NewFollower(c.TypedCache, *follow, logErrors).Run(ctx)
+		}()
+	}
+
+	if *httpAddr != "" {
+		go func() {
+			log.Printf("cache-server serving HTTP API on %s", *httpAddr)
+			if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	if *memcacheAddr != "" {
+		go func() {
+			log.Printf("cache-server serving memcached protocol on %s", *memcacheAddr)
+			if err := mcSrv.ListenAndServe(*memcacheAddr); err != nil && !errors.Is(err, memcache.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	log.Printf("cache-server listening on %s", *addr)
+	err := srv.ListenAndServe(*addr)
+	c.Close()
+	if err != nil && !errors.Is(err, resp.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ErrDiskTierOpen is returned by OpenDiskTier when the cache already has a disk tier attached.
+var ErrDiskTierOpen = errors.New("cache: disk tier already open")
+
+// diskSegments is the number of segment files the disk tier's budget is divided into.
+// Space is reclaimed a whole segment at a time, dropping its oldest demoted entries.
+const diskSegments = 8
+
+// diskRecord is an entry demoted to the disk tier.
+type diskRecord[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+	Tags      []string
+	Cost      int64
+}
+
+// diskEntry locates a demoted entry in the segment files.
+type diskEntry struct {
+	seg       *segment
+	off       int64
+	n         int64
+	expiresAt time.Time
+	tags      []string
+}
+
+// segment is one append-only file of the disk tier.
+type segment struct {
+	file *os.File
+	size int64
+}
+
+// diskTier is a size-bounded, log-structured second tier for entries evicted from memory.
+// Demoted entries are appended to the newest segment file. When the files grow past the
+// budget, the oldest segment is deleted together with the entries still in it, so the tier
+// evicts in FIFO order without ever compacting. Promoted and overwritten entries only leave
+// the index; their bytes are reclaimed when their segment is deleted.
+type diskTier[K comparable, V any] struct {
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+	segments    []*segment // oldest first
+	size        int64
+	index       map[K]diskEntry
+}
+
+// OpenDiskTier attaches a disk tier in dir, holding at most about maxBytes of entries evicted
+// from memory. An entry evicted by WithMaxEntries or WithMaxCost is demoted to the tier instead
+// of being dropped, and moved back to memory when it is accessed again, without counting as a
+// write. It returns an error for an unbounded cache. The directory is owned by the cache: files left in it by a previous run
+// are removed, and Close removes the tier's files. Values stored as interface{} must have
+// their concrete types registered with gob.Register.
+//
+// Get, GetOrLoad, Fetch, Delete, Expire, TTL and the read-modify-write operations see demoted
+// entries, as do InvalidateTag, DeletePrefix and Clear. Len, Keys, Range, the scans, Stats
+// and snapshots only cover the entries in memory.
+func (c *TypedCache[K, V]) OpenDiskTier(dir string, maxBytes int64) error {
+	if maxBytes <= 0 {
+		return fmt.Errorf("cache: disk tier size %d must be positive", maxBytes)
+	}
+	if !c.bounded() {
+		return errors.New("cache: disk tier requires WithMaxEntries or WithMaxCost")
+	}
+	d := &diskTier[K, V]{
+		dir:         dir,
+		maxBytes:    maxBytes,
+		segmentSize: max(maxBytes/diskSegments, 1),
+		index:       make(map[K]diskEntry),
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := d.removeFiles(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disk != nil {
+		return ErrDiskTierOpen
+	}
+	c.disk = d
+	return nil
+}
+
+// demote moves an evicted entry to the disk tier. Failures are reported to the error handler,
+// and the entry is then lost as it would be without a disk tier. The caller must hold c.mu.
+func (c *TypedCache[K, V]) demote(e *entry[K, V]) {
+	if e.expired(c.clock.Now()) {
+		return
+	}
+	rec := diskRecord[K, V]{Key: e.key, Value: e.value, ExpiresAt: e.expiresAt, Tags: e.tags, Cost: e.cost}
+	if err := c.disk.put(rec); err != nil {
+		c.errorHandler(fmt.Errorf("cache: demote %v: %w", e.key, err))
+	}
+}
+
+// find returns the entry stored under the key, promoting it from the disk tier if it was
+// demoted. The returned entry may be expired. The caller must hold c.mu, for writing if the
+// cache is bounded; unbounded caches have no disk tier, so find does not change them.
+func (c *TypedCache[K, V]) find(key K) (*entry[K, V], bool) {
+	if e, ok := c.store[key]; ok || c.disk == nil {
+		return e, ok
+	}
+
+	rec, ok, err := c.disk.take(key, c.clock.Now())
+	if err != nil {
+		c.errorHandler(fmt.Errorf("cache: promote %v: %w", key, err))
+	}
+	if !ok {
+		return nil, false
+	}
+	c.promote(rec)
+	e, ok := c.store[key]
+	return e, ok
+}
+
+// promote moves a record taken from the disk tier back to memory, bypassing the admission
+// policy. It is not a write: it is not counted, published or logged, so that reads are not
+// reported as writes. Like the demoted entry, the promoted one is then lost on restart
+// unless it is written again. The caller must hold c.mu for writing.
+func (c *TypedCache[K, V]) promote(rec diskRecord[K, V]) {
+	c.version++
+	c.link(rec.Key, rec.Value, rec.ExpiresAt, rec.Tags, rec.Cost)
+	c.evictOverCapacity()
+}
+
+// put appends the record to the newest segment, starting a new segment when it is full and
+// deleting the oldest ones while the tier is over its budget. A record larger than the whole
+// budget is not stored.
+func (d *diskTier[K, V]) put(rec diskRecord[K, V]) error {
+	delete(d.index, rec.Key)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&rec); err != nil {
+		return err
+	}
+	n := int64(buf.Len())
+	if n > d.maxBytes {
+		return nil
+	}
+
+	if len(d.segments) == 0 || (d.current().size > 0 && d.current().size+n > d.segmentSize) {
+		f, err := os.CreateTemp(d.dir, "*.seg")
+		if err != nil {
+			return err
+		}
+		d.segments = append(d.segments, &segment{file: f})
+	}
+	seg := d.current()
+	if _, err := seg.file.WriteAt(buf.Bytes(), seg.size); err != nil {
+		return err
+	}
+	d.index[rec.Key] = diskEntry{seg: seg, off: seg.size, n: n, expiresAt: rec.ExpiresAt, tags: rec.Tags}
+	seg.size += n
+	d.size += n
+
+	for d.size > d.maxBytes && len(d.segments) > 1 {
+		if err := d.dropOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// take removes the key from the tier and returns its record, unless it has expired.
+func (d *diskTier[K, V]) take(key K, now time.Time) (diskRecord[K, V], bool, error) {
+	var rec diskRecord[K, V]
+	de, ok := d.index[key]
+	if !ok {
+		return rec, false, nil
+	}
+	delete(d.index, key)
+	if expiredAt(de.expiresAt, now) {
+		return rec, false, nil
+	}
+
+	buf := make([]byte, de.n)
+	if _, err := de.seg.file.ReadAt(buf, de.off); err != nil {
+		return rec, false, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(&rec); err != nil {
+		return rec, false, err
+	}
+	return rec, true, nil
+}
+
+// peek returns the index entry of a live demoted key without promoting it.
+// It may be called on a nil tier.
+func (d *diskTier[K, V]) peek(key K, now time.Time) (diskEntry, bool) {
+	if d == nil {
+		return diskEntry{}, false
+	}
+	de, ok := d.index[key]
+	if !ok || expiredAt(de.expiresAt, now) {
+		return diskEntry{}, false
+	}
+	return de, true
+}
+
+// drop removes the key from the tier and reports whether it held a live entry.
+func (d *diskTier[K, V]) drop(key K, now time.Time) bool {
+	de, ok := d.index[key]
+	if !ok {
+		return false
+	}
+	delete(d.index, key)
+	return !expiredAt(de.expiresAt, now)
+}
+
+// dropFunc removes the keys for which match returns true and returns how many of them
+// were live.
+func (d *diskTier[K, V]) dropFunc(now time.Time, match func(key K, de diskEntry) bool) int {
+	removed := 0
+	for key, de := range d.index {
+		if match(key, de) {
+			delete(d.index, key)
+			if !expiredAt(de.expiresAt, now) {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// hasTag reports whether the demoted entry carries the tag.
+func (de diskEntry) hasTag(tag string) bool {
+	return slices.Contains(de.tags, tag)
+}
+
+// current returns the segment being appended to.
+func (d *diskTier[K, V]) current() *segment {
+	return d.segments[len(d.segments)-1]
+}
+
+// dropOldest deletes the oldest segment and the entries still indexed in it.
+func (d *diskTier[K, V]) dropOldest() error {
+	seg := d.segments[0]
+	d.segments = d.segments[1:]
+	d.size -= seg.size
+	for key, de := range d.index {
+		if de.seg == seg {
+			delete(d.index, key)
+		}
+	}
+	return closeAndRemove(seg.file)
+}
+
+// clear deletes every segment.
+func (d *diskTier[K, V]) clear() error {
+	clear(d.index)
+	var errs []error
+	for _, seg := range d.segments {
+		errs = append(errs, closeAndRemove(seg.file))
+	}
+	d.segments = nil
+	d.size = 0
+	return errors.Join(errs...)
+}
+
+// removeFiles deletes segment files left in the directory.
+func (d *diskTier[K, V]) removeFiles() error {
+	names, err := filepath.Glob(filepath.Join(d.dir, "*.seg"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeAndRemove closes and deletes a segment file.
+func closeAndRemove(f *os.File) error {
+	return errors.Join(f.Close(), os.Remove(f.Name()))
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskTierDemoteAndPromote(t *testing.T) {
+	clock := newFakeClock()
+	c := New(WithClock(clock), WithMaxEntries(2))
+	dir := t.TempDir()
+	if err := c.OpenDiskTier(dir, 1<<20); err != nil {
+		t.Fatalf("OpenDiskTier() error = %v", err)
+	}
+	if err := c.OpenDiskTier(dir, 1<<20); err != ErrDiskTierOpen {
+		t.Errorf("second OpenDiskTier() error = %v, want ErrDiskTierOpen", err)
+	}
+
+	c.SetWithTTL("a", "va", time.Hour)
+	c.SetWith("b", int64(1), WithTags("t"))
+	c.Set("c", "vc")
+	c.Set("d", "vd")
+
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2 entries in memory", got)
+	}
+	if ttl, ok := c.TTL("a"); !ok || ttl != time.Hour {
+		t.Errorf("TTL(a) = %v, %v, want 1h, true from the disk tier", ttl, ok)
+	}
+	if tags := c.Tags("b"); len(tags) != 1 || tags[0] != "t" {
+		t.Errorf("Tags(b) = %v, want [t] from the disk tier", tags)
+	}
+
+	if v, ok := c.Get("a"); !ok || v != "va" {
+		t.Errorf("Get(a) = %v, %v, want va, true promoted from disk", v, ok)
+	}
+	if n, err := c.Incr("b", 2); err != nil || n != 3 {
+		t.Errorf("Incr(b) = %d, %v, want 3, nil on a demoted counter", n, err)
+	}
+	if tags := c.Tags("b"); len(tags) != 1 {
+		t.Errorf("Tags(b) after promotion = %v, want [t]", tags)
+	}
+
+	// c and d are now on disk.
+	if !c.Delete("c") {
+		t.Errorf("Delete(c) = false, want true for a demoted entry")
+	}
+	if _, ok := c.Get("c"); ok {
+		t.Errorf("Get(c) hit after Delete")
+	}
+	c.Set("d", "new")
+	if v, _ := c.Get("d"); v != "new" {
+		t.Errorf("Get(d) = %v, want the value written after demotion", v)
+	}
+
+	c.SetWithTTL("e", "ve", time.Second)
+	c.Set("f", "vf")
+	c.Set("g", "vg")
+	clock.Advance(time.Second)
+	if _, ok := c.Get("e"); ok {
+		t.Errorf("Get(e) hit after its TTL, want demoted entries to expire")
+	}
+
+	c.Close()
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.seg")); len(files) != 0 {
+		t.Errorf("segment files left after Close: %v", files)
+	}
+}
+
+func TestDiskTierBulkInvalidation(t *testing.T) {
+	c := New(WithMaxEntries(1))
+	defer c.Close()
+	if err := c.OpenDiskTier(t.TempDir(), 1<<20); err != nil {
+		t.Fatalf("OpenDiskTier() error = %v", err)
+	}
+
+	c.SetWith("user:1", 1, WithTags("users"))
+	c.SetWith("user:2", 2, WithTags("users"))
+	c.Set("post:1", 1)
+	c.Set("post:2", 2)
+
+	if got := c.InvalidateTag("users"); got != 2 {
+		t.Errorf("InvalidateTag(users) = %d, want 2", got)
+	}
+	if got := c.DeletePrefix("post:"); got != 2 {
+		t.Errorf("DeletePrefix(post:) = %d, want 2", got)
+	}
+	c.Set("x", 1)
+	c.Set("y", 1)
+	c.Clear()
+	if _, ok := c.Get("x"); ok {
+		t.Errorf("Get(x) hit after Clear, want the disk tier cleared too")
+	}
+}
+
+func TestDiskTierSizeBound(t *testing.T) {
+	const maxBytes = 64 << 10
+	dir := t.TempDir()
+	c := NewTyped[string, []byte](WithMaxEntries(1))
+	defer c.Close()
+	if err := c.OpenDiskTier(dir, maxBytes); err != nil {
+		t.Fatalf("OpenDiskTier() error = %v", err)
+	}
+
+	value := []byte(strings.Repeat("x", 1000))
+	for i := range 500 {
+		c.Set(strconv.Itoa(i), value)
+	}
+
+	var size int64
+	files, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += fi.Size()
+	}
+	if size > maxBytes {
+		t.Errorf("disk tier holds %d bytes, want at most %d", size, maxBytes)
+	}
+	if _, ok := c.Get("0"); ok {
+		t.Errorf("Get(0) hit, want the oldest demoted entries dropped")
+	}
+	if v, ok := c.Get("498"); !ok || len(v) != len(value) {
+		t.Errorf("Get(498) = %d bytes, %v, want the latest demoted entry", len(v), ok)
+	}
+}
+
+func TestDiskTierOversizedOverwrite(t *testing.T) {
+	c := New(WithMaxCost(10))
+	defer c.Close()
+	if err := c.OpenDiskTier(t.TempDir(), 1<<20); err != nil {
+		t.Fatalf("OpenDiskTier() error = %v", err)
+	}
+
+	c.SetWith("k", "old", WithCost(5))
+	c.SetWith("k", "new", WithCost(50))
+	if v, ok := c.Get("k"); ok {
+		t.Errorf("Get(k) = %v after an oversized overwrite, want a miss rather than the old value", v)
+	}
+
+	// The same holds when a structure grows past the limit in place.
+	grown := New(WithMaxCost(10), WithCostFunc(func(key string, value interface{}) int64 {
+		if l, ok := value.(*list); ok {
+			return int64(l.len() * 4)
+		}
+		return 1
+	}))
+	defer grown.Close()
+	if err := grown.OpenDiskTier(t.TempDir(), 1<<20); err != nil {
+		t.Fatalf("OpenDiskTier() error = %v", err)
+	}
+	grown.LPush("l", "a", "b")
+	grown.LPush("l", "c")
+	if got, _ := grown.LRange("l", 0, -1); got != nil {
+		t.Errorf("LRange() = %v after the list outgrew the cache, want an empty list", got)
+	}
+}
+
+func TestDiskTierPromotionIsNotAWrite(t *testing.T) {
+	c := New(WithMaxEntries(1))
+	defer c.Close()
+	if err := c.OpenDiskTier(t.TempDir(), 1<<20); err != nil {
+		t.Fatalf("OpenDiskTier() error = %v", err)
+	}
+	c.Set("a", "va")
+	c.Set("b", "vb")
+
+	sets := c.Stats().Sets
+	sub := c.Subscribe(16)
+	defer sub.Close()
+	if v, ok := c.Get("a"); !ok || v != "va" {
+		t.Fatalf("Get(a) = %v, %v, want va, true promoted from disk", v, ok)
+	}
+	if got := c.Stats().Sets; got != sets {
+		t.Errorf("Stats().Sets = %d after a promotion, want %d", got, sets)
+	}
+	for len(sub.C) > 0 {
+		if ev := <-sub.C; ev.Reason == ReasonSet {
+			t.Errorf("promotion published %v for %q, want no write event", ev.Reason, ev.Key)
+		}
+	}
+}
+
+func TestDiskTierRequiresBoundedCache(t *testing.T) {
+	c := New()
+	defer c.Close()
+	if err := c.OpenDiskTier(t.TempDir(), 1<<20); err == nil {
+		t.Errorf("OpenDiskTier() on an unbounded cache error = nil, want an error")
+	}
+}
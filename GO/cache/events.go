@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Reason tells why an event was published or an entry left the cache.
+type Reason int
+
+const (
+	// ReasonSet means the entry was written or its TTL changed.
+	ReasonSet Reason = iota + 1
+	// ReasonReplaced means the value was overwritten by a new one. It is only reported to OnEvict.
+	ReasonReplaced
+	// ReasonDeleted means the entry was removed explicitly, for example by Delete or Clear.
+	ReasonDeleted
+	// ReasonExpired means the entry was removed after its TTL elapsed.
+	ReasonExpired
+	// ReasonEvicted means the entry was removed to respect the cache's capacity.
+	ReasonEvicted
+	// ReasonRejected means a new value was not stored because the admission policy enabled by
+	// WithTinyLFU preferred the entries it would have evicted. It is only reported to OnEvict.
+	ReasonRejected
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonSet:
+		return "set"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonExpired:
+		return "expired"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonRejected:
+		return "rejected"
+	}
+	return fmt.Sprintf("Reason(%d)", int(r))
+}
+
+// Event describes a change to a cache entry. For ReasonSet, Value, ExpiresAt and Tags are the
+// new value, deadline and tags; otherwise Value and ExpiresAt describe the entry that was
+// removed and Tags is nil. A zero ExpiresAt means the entry never expires.
+type Event[K comparable, V any] struct {
+	Reason    Reason
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+	Tags      []string
+}
+
+// WithOnEvict registers fn to be called whenever a value leaves the cache: when it is deleted,
+// expires, is evicted, is replaced by a new value or is rejected by the admission policy.
+// fn runs after the cache lock is released, on the goroutine that caused the removal, so it
+// may call back into the cache.
+// The key and value types must match the cache's, otherwise NewTyped panics.
+func WithOnEvict[K comparable, V any](fn func(key K, value V, reason Reason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+// Subscription receives change events from a cache. Events are delivered on C in the order the
+// changes were applied. Delivery never blocks the cache: if the buffer is full the event is
+// dropped for this subscriber and counted by Dropped, so a slow consumer should check Dropped
+// and resynchronise if it needs a complete view.
+type Subscription[K comparable, V any] struct {
+	C <-chan Event[K, V]
+
+	ch      chan Event[K, V]
+	cache   *TypedCache[K, V]
+	dropped atomic.Uint64
+}
+
+// Subscribe returns a subscription receiving every Set, Deleted, Expired and Evicted event
+// through a channel buffered to hold buffer events. Call Close to unsubscribe; closing the
+// cache closes all its subscriptions.
+func (c *TypedCache[K, V]) Subscribe(buffer int) *Subscription[K, V] {
+	ch := make(chan Event[K, V], max(buffer, 0))
+	sub := &Subscription[K, V]{C: ch, ch: ch, cache: c}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subs == nil {
+		c.subs = make(map[*Subscription[K, V]]struct{})
+	}
+	c.subs[sub] = struct{}{}
+	return sub
+}
+
+// Dropped returns how many events were discarded because the subscription's buffer was full.
+func (s *Subscription[K, V]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes C. It is safe to call Close more than once.
+func (s *Subscription[K, V]) Close() {
+	c := s.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subs[s]; ok {
+		delete(c.subs, s)
+		close(s.ch)
+	}
+}
+
+// evicted is a removal waiting to be reported to the OnEvict callback.
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason Reason
+}
+
+// notify counts a change, publishes it to subscribers and queues removals for the OnEvict
+// callback. The caller must hold c.mu for writing and release it with unlock.
+func (c *TypedCache[K, V]) notify(reason Reason, key K, value V, expiresAt time.Time, tags []string) {
+	c.countRemoval(reason)
+	if c.onEvict != nil && reason != ReasonSet {
+		c.evicted = append(c.evicted, evicted[K, V]{key: key, value: value, reason: reason})
+	}
+	if reason == ReasonReplaced || reason == ReasonRejected || len(c.subs) == 0 {
+		return
+	}
+
+	if reason == ReasonSet {
+		value = c.detach(value)
+	}
+	ev := Event[K, V]{Reason: reason, Key: key, Value: value, ExpiresAt: expiresAt, Tags: slices.Clone(tags)}
+	for sub := range c.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// unlock releases c.mu and then runs the OnEvict callback for the removals queued meanwhile.
+func (c *TypedCache[K, V]) unlock() {
+	pending := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+
+	for _, ev := range pending {
+		c.onEvict(ev.key, ev.value, ev.reason)
+	}
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+type evictedCall struct {
+	key    string
+	value  interface{}
+	reason Reason
+}
+
+func TestOnEvict(t *testing.T) {
+	clock := newFakeClock()
+	var calls []evictedCall
+	var c *Cache
+	c = New(WithClock(clock), WithMaxEntries(2), WithOnEvict(func(key string, value interface{}, reason Reason) {
+		calls = append(calls, evictedCall{key, value, reason})
+		// The callback runs without the lock held, so it may use the cache.
+		c.Len()
+	}))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("a", 2)
+	c.Set("b", 3)
+	c.Set("c", 4)
+	c.Delete("b")
+	c.SetWithTTL("d", 5, time.Second)
+	clock.Advance(time.Second)
+	c.DeleteExpired()
+
+	want := []evictedCall{
+		{"a", 1, ReasonReplaced},
+		{"a", 2, ReasonEvicted},
+		{"b", 3, ReasonDeleted},
+		{"d", 5, ReasonExpired},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("OnEvict calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("OnEvict call %d = %v, want %v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestOnEvictTypeMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewTyped with a mismatched OnEvict callback did not panic")
+		}
+	}()
+	NewTyped[int, string](WithOnEvict(func(key string, value int, reason Reason) {}))
+}
+
+func TestSubscribe(t *testing.T) {
+	clock := newFakeClock()
+	c := NewTyped[string, int](WithClock(clock), WithMaxEntries(1))
+	defer c.Close()
+
+	sub := c.Subscribe(16)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Expire("b", time.Second)
+	c.Delete("b")
+	c.Set("c", 3)
+	c.Clear()
+
+	want := []struct {
+		reason Reason
+		key    string
+		value  int
+	}{
+		{ReasonSet, "a", 1},
+		{ReasonSet, "b", 2},
+		{ReasonEvicted, "a", 1},
+		{ReasonSet, "b", 2},
+		{ReasonDeleted, "b", 2},
+		{ReasonSet, "c", 3},
+		{ReasonDeleted, "c", 3},
+	}
+	for _, w := range want {
+		select {
+		case ev := <-sub.C:
+			if ev.Reason != w.reason || ev.Key != w.key || ev.Value != w.value {
+				t.Errorf("event = %v %q %d, want %v %q %d", ev.Reason, ev.Key, ev.Value, w.reason, w.key, w.value)
+			}
+		default:
+			t.Fatalf("missing event %v %q", w.reason, w.key)
+		}
+	}
+
+	sub.Close()
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Errorf("receive after Close succeeded, want closed channel")
+	}
+	c.Set("d", 4)
+}
+
+func TestSubscribeDropsWhenFull(t *testing.T) {
+	c := NewTyped[string, int]()
+	sub := c.Subscribe(2)
+
+	for i := range 5 {
+		c.Set("k", i)
+	}
+	if got := sub.Dropped(); got != 3 {
+		t.Errorf("Dropped() = %d, want 3", got)
+	}
+	if ev := <-sub.C; ev.Value != 0 {
+		t.Errorf("first event value = %d, want 0", ev.Value)
+	}
+
+	c.Close()
+	<-sub.C
+	if _, ok := <-sub.C; ok {
+		t.Errorf("receive after cache Close succeeded, want closed channel")
+	}
+	sub.Close()
+}
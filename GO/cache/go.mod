@@ -1 +1,3 @@
 module github.com/sKrasiuk/PubRep/GO/cache
+
+go 1.24
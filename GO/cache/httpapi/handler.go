@@ -0,0 +1,293 @@
+// Package httpapi exposes a cache.Cache over HTTP with a small JSON API for inspecting
+// and modifying it in running services.
+//
+// Routes, relative to where the handler is mounted:
+//
+//	GET    /keys?prefix=&cursor=&limit=  list keys in order, paginated by cursor
+//	GET    /keys/{key}                   read an entry
+//	PUT    /keys/{key}                   write an entry from {"value": ..., "ttl": "30s"}
+//	DELETE /keys/{key}                   delete an entry
+//	GET    /stats                        cache statistics as JSON
+//	GET    /metrics                      cache statistics in the Prometheus text format
+//	POST   /flush                        remove every entry
+//
+// Mount it under a prefix with http.StripPrefix, for example
+// mux.Handle("/cache/", http.StripPrefix("/cache", httpapi.NewHandler(c))).
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// Pagination limits for the key listing.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// maxBodyBytes bounds the size of a PUT request body.
+const maxBodyBytes = 8 << 20
+
+// Register the types of decoded JSON objects and arrays so that caches holding them can be
+// saved and replicated.
+func init() {
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Option configures a handler created by NewHandler.
+type Option func(*handler)
+
+// WithBearerToken requires every request to carry "Authorization: Bearer <token>".
+func WithBearerToken(token string) Option {
+	return func(h *handler) {
+		h.token = token
+	}
+}
+
+type handler struct {
+	cache *cache.Cache
+	token string
+	mux   *http.ServeMux
+}
+
+// NewHandler returns an http.Handler serving the JSON API for c.
+func NewHandler(c *cache.Cache, opts ...Option) http.Handler {
+	h := &handler{cache: c, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /keys", h.listKeys)
+	h.mux.HandleFunc("GET /keys/{key...}", h.getKey)
+	h.mux.HandleFunc("PUT /keys/{key...}", h.putKey)
+	h.mux.HandleFunc("DELETE /keys/{key...}", h.deleteKey)
+	h.mux.HandleFunc("GET /stats", h.stats)
+	h.mux.Handle("GET /metrics", cache.MetricsHandler(c))
+	h.mux.HandleFunc("POST /flush", h.flush)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="cache"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// entryJSON is the representation of a cache entry. TTL is omitted for entries that never expire.
+type entryJSON struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	TTL   string      `json:"ttl,omitempty"`
+}
+
+// putRequest is the body of PUT /keys/{key}. An empty TTL applies the cache's default TTL.
+type putRequest struct {
+	Value json.RawMessage `json:"value"`
+	TTL   string          `json:"ttl"`
+}
+
+type listResponse struct {
+	Keys       []string `json:"keys"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type statsResponse struct {
+	Entries     int     `json:"entries"`
+	Cost        int64   `json:"cost"`
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	HitRatio    float64 `json:"hit_ratio"`
+	Sets        uint64  `json:"sets"`
+	Deletes     uint64  `json:"deletes"`
+	Evictions   uint64  `json:"evictions"`
+	Expirations uint64  `json:"expirations"`
+	Rejections  uint64  `json:"rejections"`
+}
+
+func (h *handler) getKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	v, ok := h.cache.Get(key)
+	if !ok {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	resp := entryJSON{Key: key, Value: v}
+	if ttl, ok := h.cache.TTL(key); ok && ttl != cache.NoExpiration {
+		resp.TTL = ttl.Round(time.Second).String()
+	}
+	if b, ok := v.([]byte); ok {
+		resp.Value = string(b)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) putKey(w http.ResponseWriter, r *http.Request) {
+	var req putRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if req.Value == nil {
+		writeError(w, http.StatusBadRequest, `missing "value"`)
+		return
+	}
+
+	value, err := decodeValue(req.Value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid value: "+err.Error())
+		return
+	}
+
+	ttl := cache.DefaultExpiration
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, `"ttl" must be a positive duration such as "30s"`)
+			return
+		}
+	}
+
+	h.cache.SetWithTTL(r.PathValue("key"), value, ttl)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) deleteKey(w http.ResponseWriter, r *http.Request) {
+	if !h.cache.Delete(r.PathValue("key")) {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listKeys returns keys in lexical order. The cursor is the last key of the previous page.
+func (h *handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	cursor := q.Get("cursor")
+
+	limit := DefaultLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, `"limit" must be a positive integer`)
+			return
+		}
+		limit = min(n, MaxLimit)
+	}
+
+	var keys []string
+	for _, key := range h.cache.Keys() {
+		if strings.HasPrefix(key, prefix) && key > cursor {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	resp := listResponse{Keys: keys}
+	if len(keys) > limit {
+		resp.Keys = keys[:limit]
+		resp.NextCursor = keys[limit-1]
+	}
+	if resp.Keys == nil {
+		resp.Keys = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	st := h.cache.Stats()
+	writeJSON(w, http.StatusOK, statsResponse{
+		Entries:     st.Size,
+		Cost:        st.Cost,
+		Hits:        st.Hits,
+		Misses:      st.Misses,
+		HitRatio:    st.HitRatio(),
+		Sets:        st.Sets,
+		Deletes:     st.Deletes,
+		Evictions:   st.Evictions,
+		Expirations: st.Expirations,
+		Rejections:  st.Rejections,
+	})
+}
+
+func (h *handler) flush(w http.ResponseWriter, r *http.Request) {
+	h.cache.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeValue decodes a JSON value for storage. Integral numbers become int64 so that they
+// work with the cache's integer operations; other numbers become float64. Objects and arrays
+// become map[string]interface{} and []interface{} holding values converted the same way.
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v)
+}
+
+// convertNumbers replaces the json.Number values in v, which is modified in place.
+func convertNumbers(v interface{}) (interface{}, error) {
+	var err error
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+		return x.Float64()
+	case map[string]interface{}:
+		for k, e := range x {
+			if x[k], err = convertNumbers(e); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			if x[i], err = convertNumbers(e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "value cannot be encoded as JSON: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
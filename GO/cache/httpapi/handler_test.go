@@ -0,0 +1,156 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+func do(t *testing.T, h http.Handler, method, target, body string, header ...string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var decoded map[string]interface{}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec, decoded
+}
+
+func TestKeyRoutes(t *testing.T) {
+	c := cache.New()
+	defer c.Close()
+	h := NewHandler(c)
+
+	testCases := []struct {
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantValue  interface{}
+	}{
+		{method: "GET", target: "/keys/missing", wantStatus: http.StatusNotFound},
+		{method: "PUT", target: "/keys/user:1", body: `{"value": {"name": "Ann"}}`, wantStatus: http.StatusNoContent},
+		{method: "GET", target: "/keys/user:1", wantStatus: http.StatusOK, wantValue: map[string]interface{}{"name": "Ann"}},
+		{method: "PUT", target: "/keys/n", body: `{"value": 7, "ttl": "1m"}`, wantStatus: http.StatusNoContent},
+		{method: "GET", target: "/keys/n", wantStatus: http.StatusOK, wantValue: float64(7)},
+		{method: "PUT", target: "/keys/bad", body: `{"value": 1, "ttl": "soon"}`, wantStatus: http.StatusBadRequest},
+		{method: "PUT", target: "/keys/bad", body: `{}`, wantStatus: http.StatusBadRequest},
+		{method: "DELETE", target: "/keys/user:1", wantStatus: http.StatusNoContent},
+		{method: "DELETE", target: "/keys/user:1", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		rec, body := do(t, h, tc.method, tc.target, tc.body)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.target, rec.Code, tc.wantStatus)
+			continue
+		}
+		if tc.wantValue != nil {
+			if got, _ := json.Marshal(body["value"]); string(got) != mustJSON(tc.wantValue) {
+				t.Errorf("%s %s value = %s, want %s", tc.method, tc.target, got, mustJSON(tc.wantValue))
+			}
+		}
+	}
+
+	if v, _ := c.Get("n"); v != int64(7) {
+		t.Errorf("cache.Get(n) = %#v, want int64(7)", v)
+	}
+	if _, body := do(t, h, "GET", "/keys/n", ""); body["ttl"] != "1m0s" {
+		t.Errorf("GET /keys/n ttl = %v, want 1m0s", body["ttl"])
+	}
+}
+
+func TestListKeysPagination(t *testing.T) {
+	c := cache.New()
+	defer c.Close()
+	for _, key := range []string{"user:3", "user:1", "session:1", "user:2"} {
+		c.Set(key, 1)
+	}
+	h := NewHandler(c)
+
+	_, page := do(t, h, "GET", "/keys?prefix=user:&limit=2", "")
+	if got := mustJSON(page["keys"]); got != `["user:1","user:2"]` {
+		t.Errorf("first page keys = %s, want [user:1 user:2]", got)
+	}
+	if page["next_cursor"] != "user:2" {
+		t.Fatalf("first page next_cursor = %v, want user:2", page["next_cursor"])
+	}
+
+	_, page = do(t, h, "GET", "/keys?prefix=user:&limit=2&cursor=user:2", "")
+	if got := mustJSON(page["keys"]); got != `["user:3"]` {
+		t.Errorf("second page keys = %s, want [user:3]", got)
+	}
+	if _, ok := page["next_cursor"]; ok {
+		t.Errorf("second page next_cursor = %v, want none", page["next_cursor"])
+	}
+}
+
+func TestStatsFlushAndAuth(t *testing.T) {
+	c := cache.New()
+	defer c.Close()
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("missing")
+	h := NewHandler(c, WithBearerToken("secret"))
+
+	if rec, _ := do(t, h, "GET", "/stats", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /stats without token status = %d, want 401", rec.Code)
+	}
+	if rec, _ := do(t, h, "GET", "/stats", "", "Authorization", "Bearer wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /stats with wrong token status = %d, want 401", rec.Code)
+	}
+
+	auth := []string{"Authorization", "Bearer secret"}
+	rec, stats := do(t, h, "GET", "/stats", "", auth...)
+	if rec.Code != http.StatusOK || stats["entries"] != float64(1) || stats["hits"] != float64(1) || stats["misses"] != float64(1) {
+		t.Errorf("GET /stats = %d %v, want 200 with 1 entry, 1 hit and 1 miss", rec.Code, stats)
+	}
+
+	if rec, _ := do(t, h, "POST", "/flush", "", auth...); rec.Code != http.StatusNoContent {
+		t.Errorf("POST /flush status = %d, want 204", rec.Code)
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() after flush = %d, want 0", n)
+	}
+}
+
+func mustJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestJSONValuesSurviveSnapshot(t *testing.T) {
+	c := cache.New()
+	defer c.Close()
+	h := NewHandler(c)
+	do(t, h, "PUT", "/keys/k", `{"value": {"a": 1.5, "b": [1, "x", {"c": 2}]}}`)
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	restored := cache.New()
+	defer restored.Close()
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]interface{}{"a": 1.5, "b": []interface{}{int64(1), "x", map[string]interface{}{"c": int64(2)}}}
+	if v, ok := restored.Get("k"); !ok || !reflect.DeepEqual(v, want) {
+		t.Errorf("Get(k) after Load = %#v, %v, want %#v", v, ok, want)
+	}
+}
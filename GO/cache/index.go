@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"cmp"
+	"math/rand/v2"
+)
+
+// maxSkipLevel bounds the height of skip list towers, enough for billions of keys.
+const maxSkipLevel = 32
+
+// keyIndex keeps the cache keys in sorted order so that prefix and range operations
+// can seek to their first key instead of scanning the whole store.
+// It is a skip list; every operation is O(log n) on average.
+type keyIndex[K comparable] struct {
+	compare func(a, b K) int
+	head    skipNode[K]
+	level   int
+}
+
+type skipNode[K comparable] struct {
+	key  K
+	next []*skipNode[K]
+}
+
+// newKeyIndex returns an empty index, or nil if K has no natural order.
+func newKeyIndex[K comparable]() *keyIndex[K] {
+	compare := orderedCompare[K]()
+	if compare == nil {
+		return nil
+	}
+	return &keyIndex[K]{
+		compare: compare,
+		head:    skipNode[K]{next: make([]*skipNode[K], maxSkipLevel)},
+		level:   1,
+	}
+}
+
+// orderedCompare returns cmp.Compare for K if K is one of the built-in ordered types, or nil.
+func orderedCompare[K comparable]() func(a, b K) int {
+	var f any
+	switch any(*new(K)).(type) {
+	case string:
+		f = cmp.Compare[string]
+	case int:
+		f = cmp.Compare[int]
+	case int8:
+		f = cmp.Compare[int8]
+	case int16:
+		f = cmp.Compare[int16]
+	case int32:
+		f = cmp.Compare[int32]
+	case int64:
+		f = cmp.Compare[int64]
+	case uint:
+		f = cmp.Compare[uint]
+	case uint8:
+		f = cmp.Compare[uint8]
+	case uint16:
+		f = cmp.Compare[uint16]
+	case uint32:
+		f = cmp.Compare[uint32]
+	case uint64:
+		f = cmp.Compare[uint64]
+	case float32:
+		f = cmp.Compare[float32]
+	case float64:
+		f = cmp.Compare[float64]
+	}
+	compare, _ := f.(func(a, b K) int)
+	return compare
+}
+
+// insert adds the key to the index if it is not already present.
+func (ix *keyIndex[K]) insert(key K) {
+	var update [maxSkipLevel]*skipNode[K]
+	x := &ix.head
+	for i := ix.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && ix.compare(x.next[i].key, key) < 0 {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	if next := x.next[0]; next != nil && ix.compare(next.key, key) == 0 {
+		return
+	}
+
+	level := randomLevel()
+	for i := ix.level; i < level; i++ {
+		update[i] = &ix.head
+	}
+	ix.level = max(ix.level, level)
+
+	node := &skipNode[K]{key: key, next: make([]*skipNode[K], level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+}
+
+// remove deletes the key from the index if it is present.
+func (ix *keyIndex[K]) remove(key K) {
+	x := &ix.head
+	var target *skipNode[K]
+	for i := ix.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && ix.compare(x.next[i].key, key) < 0 {
+			x = x.next[i]
+		}
+		if next := x.next[i]; next != nil && ix.compare(next.key, key) == 0 {
+			target = next
+			x.next[i] = next.next[i]
+		}
+	}
+	if target == nil {
+		return
+	}
+	for ix.level > 1 && ix.head.next[ix.level-1] == nil {
+		ix.level--
+	}
+}
+
+// seek returns the first node whose key is not less than key, or nil if there is none.
+func (ix *keyIndex[K]) seek(key K) *skipNode[K] {
+	x := &ix.head
+	for i := ix.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && ix.compare(x.next[i].key, key) < 0 {
+			x = x.next[i]
+		}
+	}
+	return x.next[0]
+}
+
+// first returns the node with the smallest key, or nil if the index is empty.
+func (ix *keyIndex[K]) first() *skipNode[K] {
+	return ix.head.next[0]
+}
+
+// clear removes every key from the index.
+func (ix *keyIndex[K]) clear() {
+	clear(ix.head.next)
+	ix.level = 1
+}
+
+// randomLevel picks a tower height with a 1/4 chance of growing each level.
+func randomLevel() int {
+	level := 1
+	for level < maxSkipLevel && rand.Uint32()&3 == 0 {
+		level++
+	}
+	return level
+}
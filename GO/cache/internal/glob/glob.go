@@ -0,0 +1,104 @@
+// Package glob matches strings against Redis-style glob patterns, as used for key listings
+// and pub/sub channel patterns.
+package glob
+
+import "strings"
+
+// Match reports whether s matches a Redis-style glob pattern supporting
+// '*', '?', character classes such as [abc], [^a] and [a-z], and '\' escapes.
+// It runs in O(len(pattern) * len(s)) time whatever the pattern.
+func Match(pattern, s string) bool {
+	// When the pattern after a '*' fails to match, only the last '*' needs to be retried,
+	// one byte further into s: earlier stars can never need to cover more.
+	var starPattern, starS string
+	star := false
+	for {
+		if len(pattern) > 0 && pattern[0] == '*' {
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			starPattern, starS, star = pattern, s, true
+			continue
+		}
+		if len(pattern) == 0 && len(s) == 0 {
+			return true
+		}
+		if len(pattern) > 0 && len(s) > 0 {
+			if rest, ok := matchByte(pattern, s[0]); ok {
+				pattern, s = rest, s[1:]
+				continue
+			}
+		}
+		if !star || len(starS) == 0 {
+			return false
+		}
+		starS = starS[1:]
+		pattern, s = starPattern, starS
+	}
+}
+
+// matchByte matches c against the pattern's first element, which is not '*', and returns
+// the pattern after it.
+func matchByte(pattern string, c byte) (rest string, ok bool) {
+	switch pattern[0] {
+	case '?':
+		return pattern[1:], true
+	case '[':
+		matched, rest, ok := matchClass(pattern[1:], c)
+		if !ok {
+			return pattern[1:], c == '['
+		}
+		return rest, matched
+	case '\\':
+		if len(pattern) > 1 {
+			pattern = pattern[1:]
+		}
+	}
+	return pattern[1:], c == pattern[0]
+}
+
+// matchClass matches c against the class body following '[' and returns the pattern after ']'.
+// ok is false if the class is not terminated, in which case '[' is taken literally.
+func matchClass(class string, c byte) (matched bool, rest string, ok bool) {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+
+	for i := 0; i < len(class); i++ {
+		switch {
+		case class[i] == ']':
+			return matched != negate, class[i+1:], true
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			if class[i] == c {
+				matched = true
+			}
+		case i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']':
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+		default:
+			if class[i] == c {
+				matched = true
+			}
+		}
+	}
+	return false, "", false
+}
+
+// quoter escapes the characters that have a meaning in patterns.
+var quoter = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+
+// Quote returns a pattern matching s and nothing else.
+func Quote(s string) string {
+	return quoter.Replace(s)
+}
@@ -0,0 +1,55 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "session:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"a/*", "a/b/c", true},
+		{Quote("news.[*]?"), "news.[*]?", true},
+		{Quote("news.[*]?"), "news.a*b", false},
+		{Quote(`a\b`), `a\b`, true},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+		{"*a", "bab", false},
+		{"**", "", true},
+		{"a[b", "a[b", true},
+		{"a[b", "ab", false},
+		{"[a-c]*", "", false},
+	}
+
+	for _, tc := range testCases {
+		if got := Match(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestMatchPathological(t *testing.T) {
+	pattern := strings.Repeat("*a", 30) + "*b"
+	s := strings.Repeat("a", 100)
+	start := time.Now()
+	if Match(pattern, s) {
+		t.Errorf("Match(%q, %q) = true, want false", pattern, s)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Match took %v, want linear time", elapsed)
+	}
+}
@@ -0,0 +1,51 @@
+package cache
+
+import "time"
+
+// Clock is the time source used by the cache to decide when entries expire.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock backed by time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// janitor periodically runs a maintenance task, such as sweeping expired entries
+// or writing snapshots, until stopped.
+type janitor struct {
+	interval time.Duration
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+func newJanitor(interval time.Duration) *janitor {
+	return &janitor{
+		interval: interval,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (j *janitor) run(task func()) {
+	defer close(j.stopped)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			task()
+		case <-j.done:
+			return
+		}
+	}
+}
+
+// stop signals the janitor goroutine to exit and waits until it has.
+func (j *janitor) stop() {
+	close(j.done)
+	<-j.stopped
+}
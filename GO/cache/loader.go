@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound can be returned by a loader to report that the key does not exist.
+// Such results are remembered by GetOrLoad when WithNegativeTTL is configured.
+var ErrNotFound = errors.New("cache: not found")
+
+// LoaderFunc produces the value for a key that is missing from the cache.
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// call is a load in progress shared by every GetOrLoad caller of the same key.
+type call[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	refresh bool // started in the background to refresh an entry; errors go to the error handler
+}
+
+// failure is a remembered loader error together with the time it stops being served.
+type failure struct {
+	err       error
+	expiresAt time.Time
+}
+
+// GetOrLoad returns the value stored under the key, calling loader to produce it on a miss.
+// Concurrent callers for the same key share a single loader call and receive its result.
+// The loader runs with a context that is not canceled when ctx is, so a caller giving up
+// does not abort the load for the others; the caller itself returns ctx.Err().
+// Successful results are stored with the default TTL. Errors are returned to every waiting
+// caller and are not cached unless WithNegativeTTL or WithErrorTTL is configured.
+// A panicking loader is reported as an error.
+//
+// With WithRefreshAhead or WithStaleWhileRevalidate, a loaded entry close to or shortly past
+// its expiry is returned immediately while loader refreshes it in the background.
+func (c *TypedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	v, ok, refresh := c.getLoaded(key)
+	c.countLookup(ok)
+	if ok {
+		if refresh {
+			c.refresh(ctx, key, loader)
+		}
+		return v, nil
+	}
+
+	c.flightMu.Lock()
+	// getLoaded has already recorded this access with the admission policy.
+	if v, ok := c.lookup(key, false); ok {
+		c.flightMu.Unlock()
+		return v, nil
+	}
+	if err := c.cachedFailure(key); err != nil {
+		c.flightMu.Unlock()
+		var zero V
+		return zero, err
+	}
+
+	cl, ok := c.calls[key]
+	if !ok {
+		cl = c.startLoad(ctx, key, loader, false)
+	}
+	c.flightMu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// getLoaded looks up the key for GetOrLoad. Besides live entries it returns loaded entries
+// within their stale grace period, and it reports whether the entry should be refreshed.
+func (c *TypedCache[K, V]) getLoaded(key K) (v V, ok, refresh bool) {
+	if c.bounded() {
+		c.mu.Lock()
+		defer c.unlock()
+	} else {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	if c.lfu != nil {
+		c.lfu.record(key)
+	}
+	e, found := c.find(key)
+	if !found {
+		return v, false, false
+	}
+	now := c.clock.Now()
+	switch {
+	case !e.expired(now):
+		refresh = e.loaded && c.refreshAhead > 0 && !e.expiresAt.IsZero() && e.expiresAt.Sub(now) <= c.refreshAhead
+	case c.stale(e, now):
+		refresh = true
+	default:
+		return v, false, false
+	}
+	if c.bounded() {
+		c.lru.moveToFront(e)
+	}
+	return c.detach(e.value), true, refresh
+}
+
+// stale reports whether an expired entry may still be served by GetOrLoad.
+func (c *TypedCache[K, V]) stale(e *entry[K, V], now time.Time) bool {
+	return e.loaded && c.staleGrace > 0 && e.expired(now) && now.Before(e.expiresAt.Add(c.staleGrace))
+}
+
+// refresh reloads the key in the background unless a load of it is already running or its
+// last failure is still remembered.
+func (c *TypedCache[K, V]) refresh(ctx context.Context, key K, loader LoaderFunc[K, V]) {
+	if c.cachedFailure(key) != nil {
+		return
+	}
+	c.flightMu.Lock()
+	defer c.flightMu.Unlock()
+	if _, ok := c.calls[key]; !ok {
+		c.startLoad(ctx, key, loader, true)
+	}
+}
+
+// startLoad registers a call for the key and runs loader for it. The caller must hold c.flightMu.
+func (c *TypedCache[K, V]) startLoad(ctx context.Context, key K, loader LoaderFunc[K, V], refresh bool) *call[V] {
+	cl := &call[V]{done: make(chan struct{}), refresh: refresh}
+	if c.calls == nil {
+		c.calls = make(map[K]*call[V])
+	}
+	c.calls[key] = cl
+	go c.load(context.WithoutCancel(ctx), key, loader, cl)
+	return cl
+}
+
+// load runs the loader, publishes its result to the cache and wakes the waiting callers.
+func (c *TypedCache[K, V]) load(ctx context.Context, key K, loader LoaderFunc[K, V], cl *call[V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			cl.err = fmt.Errorf("cache: loader panicked: %v", r)
+		}
+
+		c.mu.Lock()
+		if cl.err == nil {
+			c.set(key, cl.value, c.deadline(DefaultExpiration))
+			if e, ok := c.store[key]; ok {
+				e.loaded = true
+			}
+		} else if ttl := c.failureTTL(cl.err); ttl > 0 {
+			if c.failures == nil {
+				c.failures = make(map[K]failure)
+			}
+			c.failures[key] = failure{err: cl.err, expiresAt: c.clock.Now().Add(ttl)}
+		}
+		c.unlock()
+
+		c.flightMu.Lock()
+		delete(c.calls, key)
+		c.flightMu.Unlock()
+		close(cl.done)
+
+		if cl.refresh && cl.err != nil {
+			c.errorHandler(fmt.Errorf("cache: refresh %v: %w", key, cl.err))
+		}
+	}()
+
+	cl.value, cl.err = loader(ctx, key)
+}
+
+// cachedFailure returns the remembered loader error for the key, if it is still current.
+func (c *TypedCache[K, V]) cachedFailure(key K) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.failures[key]
+	if !ok || !c.clock.Now().Before(f.expiresAt) {
+		return nil
+	}
+	return f.err
+}
+
+// failureTTL returns how long a loader error should be remembered.
+func (c *TypedCache[K, V]) failureTTL(err error) time.Duration {
+	if errors.Is(err, ErrNotFound) {
+		return c.negativeTTL
+	}
+	return c.errorTTL
+}
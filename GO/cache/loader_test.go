@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCoalescesCalls(t *testing.T) {
+	c := NewTyped[string, int]()
+	defer c.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make(chan int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "k", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad() error = %v", err)
+			}
+			results <- v
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("loader called %d times, want 1", got)
+	}
+	for v := range results {
+		if v != 42 {
+			t.Errorf("GetOrLoad() = %d, want 42", v)
+		}
+	}
+	if v, ok := c.Get("k"); !ok || v != 42 {
+		t.Errorf("Get(k) = %v, %v, want 42, true", v, ok)
+	}
+}
+
+func TestGetOrLoadContextCancel(t *testing.T) {
+	c := NewTyped[string, int]()
+	defer c.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.GetOrLoad(ctx, "k", func(ctx context.Context, key string) (int, error) {
+		<-release
+		return 1, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GetOrLoad() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestGetOrLoadErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	testCases := []struct {
+		name      string
+		opts      []Option
+		err       error
+		wantCalls int32
+	}{
+		{name: "errors are not cached by default", err: errBoom, wantCalls: 2},
+		{name: "errors cached with error ttl", opts: []Option{WithErrorTTL(time.Minute)}, err: errBoom, wantCalls: 1},
+		{name: "not found cached with negative ttl", opts: []Option{WithNegativeTTL(time.Minute)}, err: ErrNotFound, wantCalls: 1},
+		{name: "negative ttl ignores other errors", opts: []Option{WithNegativeTTL(time.Minute)}, err: errBoom, wantCalls: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewTyped[string, int](tc.opts...)
+			defer c.Close()
+
+			var calls atomic.Int32
+			loader := func(ctx context.Context, key string) (int, error) {
+				calls.Add(1)
+				return 0, tc.err
+			}
+
+			for i := 0; i < 2; i++ {
+				if _, err := c.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, tc.err) {
+					t.Errorf("GetOrLoad() error = %v, want %v", err, tc.err)
+				}
+			}
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("loader called %d times, want %d", got, tc.wantCalls)
+			}
+
+			c.Set("k", 7)
+			if v, err := c.GetOrLoad(context.Background(), "k", loader); err != nil || v != 7 {
+				t.Errorf("GetOrLoad() after Set = %v, %v, want 7, nil", v, err)
+			}
+		})
+	}
+}
+
+// waitForValue polls until the cache holds want under the key.
+func waitForValue(t *testing.T, c *TypedCache[string, int], key string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, ok := c.Get(key); ok && v == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache never held %d under %q", want, key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	clock := newFakeClock()
+	c := NewTyped[string, int](WithClock(clock), WithDefaultTTL(time.Minute), WithRefreshAhead(10*time.Second))
+	defer c.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{}, 1)
+	loader := func(ctx context.Context, key string) (int, error) {
+		n := int(calls.Add(1))
+		if n > 1 {
+			<-release
+		}
+		return n, nil
+	}
+	ctx := context.Background()
+
+	c.GetOrLoad(ctx, "k", loader)
+	clock.Advance(45 * time.Second)
+	if v, _ := c.GetOrLoad(ctx, "k", loader); v != 1 || calls.Load() != 1 {
+		t.Errorf("GetOrLoad() outside the window = %d after %d loads, want 1 without refreshing", v, calls.Load())
+	}
+
+	clock.Advance(10 * time.Second)
+	for range 5 {
+		if v, err := c.GetOrLoad(ctx, "k", loader); err != nil || v != 1 {
+			t.Errorf("GetOrLoad() inside the window = %d, %v, want the current value 1", v, err)
+		}
+	}
+	release <- struct{}{}
+	waitForValue(t, c, "k", 2)
+	if got := calls.Load(); got != 2 {
+		t.Errorf("loader called %d times, want a single refresh", got)
+	}
+	if ttl, _ := c.TTL("k"); ttl != time.Minute {
+		t.Errorf("TTL(k) after refresh = %v, want a fresh 1m", ttl)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	var reported atomic.Int32
+	c := NewTyped[string, int](
+		WithClock(clock),
+		WithDefaultTTL(time.Minute),
+		WithStaleWhileRevalidate(30*time.Second),
+		WithErrorHandler(func(error) { reported.Add(1) }),
+	)
+	defer c.Close()
+
+	var calls atomic.Int32
+	fail := errors.New("backend down")
+	release := make(chan error)
+	loader := func(ctx context.Context, key string) (int, error) {
+		n := int(calls.Add(1))
+		if n == 1 {
+			return n, nil
+		}
+		if err := <-release; err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+	ctx := context.Background()
+
+	c.GetOrLoad(ctx, "k", loader)
+	clock.Advance(time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Errorf("Get(k) hit after expiry, want stale values only served by GetOrLoad")
+	}
+	if n := c.DeleteExpired(); n != 0 {
+		t.Errorf("DeleteExpired() = %d, want stale entry kept during its grace period", n)
+	}
+	if v, err := c.GetOrLoad(ctx, "k", loader); err != nil || v != 1 {
+		t.Errorf("GetOrLoad() within grace = %d, %v, want stale 1", v, err)
+	}
+	release <- fail
+	deadline := time.Now().Add(time.Second)
+	for reported.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if reported.Load() != 1 {
+		t.Errorf("refresh failure reported %d times, want 1", reported.Load())
+	}
+
+	if v, err := c.GetOrLoad(ctx, "k", loader); err != nil || v != 1 {
+		t.Errorf("GetOrLoad() after a failed refresh = %d, %v, want stale 1", v, err)
+	}
+	release <- nil
+	waitForValue(t, c, "k", 3)
+
+	clock.Advance(90 * time.Second)
+	go func() { release <- nil }()
+	if v, err := c.GetOrLoad(ctx, "k", loader); err != nil || v != 4 {
+		t.Errorf("GetOrLoad() past grace = %d, %v, want a blocking load of 4", v, err)
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"time"
+)
+
+// ErrLeaseLost is returned when renewing or releasing a lease that has expired or been
+// taken over, so that its holder no longer owns the lock.
+var ErrLeaseLost = errors.New("cache: lease lost")
+
+// leaseToken is the value stored under a locked key.
+type leaseToken string
+
+func init() {
+	gob.Register(leaseToken(""))
+}
+
+// Lease is a lock on a key held until it is released or its TTL elapses. Its Token
+// identifies the holder: only the holder can renew or release the lock, and once the lease
+// has expired and the key is locked again the old token is refused.
+type Lease struct {
+	Key   string
+	Token string
+	cache *Cache
+}
+
+// TryLock locks the key for ttl without waiting and reports whether it succeeded. It fails
+// while the key holds another live value, whether a lease or not, and when the lease is not
+// stored because of the cache's limits or admission policy. The ttl is interpreted like the
+// one of SetWithTTL; a lease that never expires is only freed by Release.
+//
+// Leases are entries like any other, so on a cache bounded with WithMaxEntries or WithMaxCost
+// a lease can be evicted before it expires, which frees the lock while its holder still
+// relies on it. Keep locks in an unbounded cache when mutual exclusion matters.
+func (c *Cache) TryLock(key string, ttl time.Duration) (*Lease, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if e, ok := c.find(key); ok && !e.expired(c.clock.Now()) {
+		return nil, false
+	}
+	token := rand.Text()
+	c.set(key, leaseToken(token), c.deadline(ttl))
+	if _, ok := c.lease(key, token); !ok {
+		return nil, false
+	}
+	return &Lease{Key: key, Token: token, cache: c}, true
+}
+
+// RenewLease extends the lock on the key held with token to expire ttl from now.
+// It returns ErrLeaseLost if the token no longer holds the lock, including when the renewed
+// lease could not be stored because of the cache's limits.
+func (c *Cache) RenewLease(key, token string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if _, ok := c.lease(key, token); !ok {
+		return ErrLeaseLost
+	}
+	c.set(key, leaseToken(token), c.deadline(ttl))
+	if _, ok := c.lease(key, token); !ok {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReleaseLease unlocks the key held with token.
+// It returns ErrLeaseLost if the token no longer holds the lock.
+func (c *Cache) ReleaseLease(key, token string) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.lease(key, token)
+	if !ok {
+		return ErrLeaseLost
+	}
+	c.removeEntry(e, ReasonDeleted)
+	return nil
+}
+
+// lease returns the entry of the live lock on the key held with token. The caller must hold c.mu.
+func (c *Cache) lease(key, token string) (*entry[string, interface{}], bool) {
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) || e.value != leaseToken(token) {
+		return nil, false
+	}
+	return e, true
+}
+
+// Renew extends the lease to expire ttl from now. It returns ErrLeaseLost if the lease has
+// expired or been released.
+func (l *Lease) Renew(ttl time.Duration) error {
+	return l.cache.RenewLease(l.Key, l.Token, ttl)
+}
+
+// Release unlocks the key. It returns ErrLeaseLost if the lease has expired or been released.
+func (l *Lease) Release() error {
+	return l.cache.ReleaseLease(l.Key, l.Token)
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	clock := newFakeClock()
+	c := New(WithClock(clock))
+	defer c.Close()
+
+	lease, ok := c.TryLock("job", time.Minute)
+	if !ok || lease.Token == "" {
+		t.Fatalf("TryLock() on a free key = %v, %v, want a lease", lease, ok)
+	}
+	if _, ok := c.TryLock("job", time.Minute); ok {
+		t.Errorf("TryLock() on a locked key succeeded")
+	}
+	c.Set("taken", 1)
+	if _, ok := c.TryLock("taken", time.Minute); ok {
+		t.Errorf("TryLock() on a key holding a value succeeded")
+	}
+
+	clock.Advance(50 * time.Second)
+	if err := lease.Renew(time.Minute); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+	clock.Advance(50 * time.Second)
+	if _, ok := c.TryLock("job", time.Minute); ok {
+		t.Errorf("TryLock() succeeded on a renewed lease")
+	}
+
+	if err := c.ReleaseLease("job", "forged"); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("ReleaseLease() with a wrong token error = %v, want ErrLeaseLost", err)
+	}
+	if err := lease.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := lease.Release(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("second Release() error = %v, want ErrLeaseLost", err)
+	}
+
+	// An expired lease cannot be renewed once another holder has taken the lock.
+	first, _ := c.TryLock("job", time.Minute)
+	clock.Advance(2 * time.Minute)
+	second, ok := c.TryLock("job", time.Minute)
+	if !ok {
+		t.Fatalf("TryLock() after the lease expired failed")
+	}
+	if err := first.Renew(time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Renew() of a lost lease error = %v, want ErrLeaseLost", err)
+	}
+	if err := first.Release(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Release() of a lost lease error = %v, want ErrLeaseLost", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Release() by the new holder error = %v", err)
+	}
+}
+
+func TestTryLockIsExclusive(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	var holders, counter atomic.Int64
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for acquired := 0; acquired < 50; {
+				lease, ok := c.TryLock("mutex", time.Minute)
+				if !ok {
+					continue
+				}
+				if holders.Add(1) != 1 {
+					t.Errorf("two goroutines hold the lock")
+				}
+				counter.Add(1)
+				holders.Add(-1)
+				if err := lease.Release(); err != nil {
+					t.Errorf("Release() error = %v", err)
+				}
+				acquired++
+			}
+		}()
+	}
+	wg.Wait()
+	if got := counter.Load(); got != 400 {
+		t.Errorf("lock was acquired %d times, want 400", got)
+	}
+}
+
+func TestTryLockNotStored(t *testing.T) {
+	c := New(WithMaxEntries(2), WithTinyLFU())
+	defer c.Close()
+	c.Set("a", 1)
+	c.Set("b", 2)
+	for range 5 {
+		c.Get("a")
+		c.Get("b")
+	}
+
+	// The admission policy prefers the frequently read entries over the new lease.
+	for range 2 {
+		if lease, ok := c.TryLock("lock", time.Minute); ok {
+			t.Errorf("TryLock() = %v, true although the lease was rejected", lease)
+		}
+	}
+	if _, ok := c.Get("lock"); ok {
+		t.Errorf("Get(lock) found a rejected lease")
+	}
+
+	costly := New(WithMaxCost(10), WithCostFunc(func(key string, value interface{}) int64 {
+		if key == "big" {
+			return 100
+		}
+		return 1
+	}))
+	defer costly.Close()
+	if _, ok := costly.TryLock("big", time.Minute); ok {
+		t.Errorf("TryLock() succeeded for a lease over the cost limit")
+	}
+}
@@ -0,0 +1,51 @@
+package cache
+
+// lruList is an intrusive doubly linked list of entries ordered from most to least recently used.
+// Linking entries directly keeps promotion and eviction O(1) without extra allocations.
+type lruList[K comparable, V any] struct {
+	head *entry[K, V]
+	tail *entry[K, V]
+}
+
+// pushFront inserts the entry as the most recently used one.
+func (l *lruList[K, V]) pushFront(e *entry[K, V]) {
+	e.prev = nil
+	e.next = l.head
+	if l.head != nil {
+		l.head.prev = e
+	}
+	l.head = e
+	if l.tail == nil {
+		l.tail = e
+	}
+}
+
+// remove unlinks the entry from the list.
+func (l *lruList[K, V]) remove(e *entry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		l.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		l.tail = e.prev
+	}
+	e.prev = nil
+	e.next = nil
+}
+
+// moveToFront marks the entry as the most recently used one.
+func (l *lruList[K, V]) moveToFront(e *entry[K, V]) {
+	if l.head == e {
+		return
+	}
+	l.remove(e)
+	l.pushFront(e)
+}
+
+// back returns the least recently used entry, or nil if the list is empty.
+func (l *lruList[K, V]) back() *entry[K, V] {
+	return l.tail
+}
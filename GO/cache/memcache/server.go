@@ -0,0 +1,512 @@
+// Package memcache serves a cache.Cache over TCP using the memcached ASCII protocol,
+// so that existing memcached clients can read and write the cache.
+package memcache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+	"github.com/sKrasiuk/PubRep/GO/cache/internal/tcpserver"
+)
+
+// Version is reported by the version and stats commands.
+const Version = "1.6.0-pubrep"
+
+// Protocol limits taken from memcached.
+const (
+	maxKeyLen     = 250
+	maxValueLen   = 1 << 20
+	maxLineLen    = 2048
+	relativeLimit = 30 * 24 * 60 * 60
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Close has been called.
+var ErrServerClosed = tcpserver.ErrClosed
+
+// Item is the value stored for entries written with non-zero client flags.
+// Entries with zero flags are stored as plain []byte so that Go code and other
+// front ends can read them directly.
+type Item struct {
+	Flags uint32
+	Value []byte
+}
+
+func init() {
+	gob.Register(Item{})
+}
+
+// Server answers memcached text protocol requests against a cache. The cache entry version
+// is used as the CAS unique value returned by gets and checked by cas.
+type Server struct {
+	cache   *cache.Cache
+	tcp     *tcpserver.Server
+	started time.Time
+
+	totalConns atomic.Uint64
+	cmdGet     atomic.Uint64
+	cmdSet     atomic.Uint64
+	getHits    atomic.Uint64
+	getMisses  atomic.Uint64
+
+	mu         sync.Mutex
+	flushTimer *time.Timer // pending delayed flush_all
+}
+
+// NewServer creates a server backed by c. The caller remains responsible for closing c.
+func NewServer(c *cache.Cache) *Server {
+	s := &Server{cache: c, started: time.Now()}
+	s.tcp = tcpserver.New(s.serveConn)
+	return s
+}
+
+// ListenAndServe listens on the TCP address addr and serves connections until Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and handles each on its own goroutine until Close is called.
+// It always returns a non-nil error; after Close it returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	return s.tcp.Serve(l)
+}
+
+// Close stops all listeners, closes open connections and waits for their handlers to return.
+// A pending delayed flush_all is cancelled.
+func (s *Server) Close() error {
+	err := s.tcp.Close()
+	s.mu.Lock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// clientError is reported to the client as CLIENT_ERROR and does not close the connection
+// unless the request stream can no longer be parsed.
+type clientError struct {
+	msg   string
+	fatal bool
+}
+
+func (e *clientError) Error() string { return e.msg }
+
+// serverError is reported to the client as SERVER_ERROR, after which the connection is
+// closed because the rest of the request cannot be interpreted.
+type serverError struct {
+	msg string
+}
+
+func (e *serverError) Error() string { return e.msg }
+
+// serveConn reads commands from the connection and writes their replies until it is closed.
+func (s *Server) serveConn(conn net.Conn) {
+	s.totalConns.Add(1)
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := readLine(r)
+		var cerr *clientError
+		if errors.As(err, &cerr) {
+			w.WriteString("CLIENT_ERROR " + cerr.msg + "\r\n")
+			w.Flush()
+			return
+		}
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			w.WriteString("ERROR\r\n")
+			w.Flush()
+			continue
+		}
+
+		quit, err := s.dispatch(r, w, args)
+		var serr *serverError
+		if errors.As(err, &cerr) {
+			w.WriteString("CLIENT_ERROR " + cerr.msg + "\r\n")
+			if cerr.fatal {
+				w.Flush()
+				return
+			}
+		} else if errors.As(err, &serr) {
+			w.WriteString("SERVER_ERROR " + serr.msg + "\r\n")
+			w.Flush()
+			return
+		} else if err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+// readLine reads a command line of at most maxLineLen bytes.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		frag, err := r.ReadSlice('\n')
+		line = append(line, frag...)
+		if len(line) > maxLineLen {
+			return "", &clientError{msg: "line too long", fatal: true}
+		}
+		if err != bufio.ErrBufferFull {
+			return string(line), err
+		}
+	}
+}
+
+// dispatch runs a single command and reports whether the connection should be closed.
+func (s *Server) dispatch(r *bufio.Reader, w *bufio.Writer, args []string) (bool, error) {
+	switch args[0] {
+	case "get", "gets":
+		s.get(w, args[1:], args[0] == "gets")
+	case "set", "add", "replace", "cas":
+		return false, s.store(r, w, args)
+	case "delete":
+		return false, s.delete(w, args[1:])
+	case "incr", "decr":
+		return false, s.incr(w, args[1:], args[0] == "decr")
+	case "flush_all":
+		return false, s.flushAll(w, args[1:])
+	case "stats":
+		s.stats(w)
+	case "version":
+		w.WriteString("VERSION " + Version + "\r\n")
+	case "quit":
+		return true, nil
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+	return false, nil
+}
+
+func (s *Server) get(w *bufio.Writer, keys []string, withCAS bool) {
+	for _, key := range keys {
+		s.cmdGet.Add(1)
+		v, version, ok := s.cache.GetWithVersion(key)
+		var flags uint32
+		var data []byte
+		if ok {
+			flags, data, ok = decodeValue(v)
+		}
+		if !ok {
+			s.getMisses.Add(1)
+			continue
+		}
+		s.getHits.Add(1)
+
+		fmt.Fprintf(w, "VALUE %s %d %d", key, flags, len(data))
+		if withCAS {
+			fmt.Fprintf(w, " %d", version)
+		}
+		w.WriteString("\r\n")
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("END\r\n")
+}
+
+// store handles set, add, replace and cas, which share the same request layout:
+// <command> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]\r\n<data>\r\n
+func (s *Server) store(r *bufio.Reader, w *bufio.Writer, args []string) error {
+	cmd := args[0]
+	want := 5
+	if cmd == "cas" {
+		want = 6
+	}
+	if len(args) < want || len(args) > want+1 {
+		return &clientError{msg: "bad command line format", fatal: true}
+	}
+	noreply := len(args) == want+1 && args[want] == "noreply"
+
+	key := args[1]
+	flags, err1 := strconv.ParseUint(args[2], 10, 32)
+	exptime, err2 := strconv.ParseInt(args[3], 10, 64)
+	size, err3 := strconv.Atoi(args[4])
+	if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+		return &clientError{msg: "bad command line format", fatal: true}
+	}
+	if size > maxValueLen {
+		// Checked before allocating, as the size comes straight from the client.
+		return &serverError{msg: "object too large for cache"}
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		return &clientError{msg: "bad data chunk"}
+	}
+	data = data[:size]
+
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	s.cmdSet.Add(1)
+
+	value := encodeValue(uint32(flags), data)
+	ttl, expired := expiration(exptime)
+
+	var reply string
+	switch cmd {
+	case "set":
+		reply = "STORED"
+		if expired {
+			s.cache.Delete(key)
+		} else {
+			s.cache.SetWithTTL(key, value, ttl)
+		}
+	case "add":
+		reply = "NOT_STORED"
+		if s.cache.SetIfAbsent(key, value, ttl) {
+			reply = "STORED"
+			if expired {
+				s.cache.Delete(key)
+			}
+		}
+	case "replace":
+		reply = "NOT_STORED"
+		if s.cache.Replace(key, value, ttl) {
+			reply = "STORED"
+			if expired {
+				s.cache.Delete(key)
+			}
+		}
+	case "cas":
+		unique, err := strconv.ParseUint(args[5], 10, 64)
+		if err != nil {
+			return &clientError{msg: "bad command line format"}
+		}
+		switch err := s.cache.SetIfVersion(key, value, ttl, unique); {
+		case errors.Is(err, cache.ErrNotFound):
+			reply = "NOT_FOUND"
+		case errors.Is(err, cache.ErrVersionMismatch):
+			reply = "EXISTS"
+		default:
+			reply = "STORED"
+			if expired {
+				s.cache.Delete(key)
+			}
+		}
+	}
+
+	if !noreply {
+		w.WriteString(reply + "\r\n")
+	}
+	return nil
+}
+
+func (s *Server) delete(w *bufio.Writer, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return &clientError{msg: "bad command line format"}
+	}
+	noreply := len(args) == 2 && args[1] == "noreply"
+
+	reply := "NOT_FOUND"
+	if s.cache.Delete(args[0]) {
+		reply = "DELETED"
+	}
+	if !noreply {
+		w.WriteString(reply + "\r\n")
+	}
+	return nil
+}
+
+// incr handles incr and decr. As in memcached, the value must be a decimal unsigned 64-bit
+// integer; incr wraps around on overflow and decr stops at zero.
+func (s *Server) incr(w *bufio.Writer, args []string, decr bool) error {
+	if len(args) < 2 || len(args) > 3 {
+		return &clientError{msg: "bad command line format"}
+	}
+	noreply := len(args) == 3 && args[2] == "noreply"
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return &clientError{msg: "invalid numeric delta argument"}
+	}
+
+	key := args[0]
+	for {
+		v, version, ok := s.cache.GetWithVersion(key)
+		var flags uint32
+		var data []byte
+		if ok {
+			flags, data, ok = decodeValue(v)
+		}
+		if !ok {
+			if !noreply {
+				w.WriteString("NOT_FOUND\r\n")
+			}
+			return nil
+		}
+
+		n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return &clientError{msg: "cannot increment or decrement non-numeric value"}
+		}
+		switch {
+		case !decr:
+			n += delta
+		case delta > n:
+			n = 0
+		default:
+			n -= delta
+		}
+
+		result := strconv.FormatUint(n, 10)
+		err = s.cache.SetIfVersion(key, encodeValue(flags, []byte(result)), cache.KeepTTL, version)
+		if errors.Is(err, cache.ErrVersionMismatch) {
+			continue
+		}
+		if err != nil {
+			result = "NOT_FOUND"
+		}
+		if !noreply {
+			w.WriteString(result + "\r\n")
+		}
+		return nil
+	}
+}
+
+func (s *Server) flushAll(w *bufio.Writer, args []string) error {
+	noreply := len(args) > 0 && args[len(args)-1] == "noreply"
+	if noreply {
+		args = args[:len(args)-1]
+	}
+	if len(args) > 1 {
+		return &clientError{msg: "bad command line format"}
+	}
+
+	exptime := int64(0)
+	if len(args) == 1 {
+		d, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || d < 0 {
+			return &clientError{msg: "bad command line format"}
+		}
+		exptime = d
+	}
+
+	// The delay is read like an exptime. A new flush_all replaces a pending delayed one.
+	delay, now := expiration(exptime)
+	s.mu.Lock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	switch {
+	case exptime == 0 || now:
+		s.cache.Clear()
+	case delay != cache.NoExpiration:
+		s.flushTimer = time.AfterFunc(delay, s.cache.Clear)
+	}
+	s.mu.Unlock()
+	if !noreply {
+		w.WriteString("OK\r\n")
+	}
+	return nil
+}
+
+func (s *Server) stats(w *bufio.Writer) {
+	now := time.Now()
+	stats := []struct {
+		name  string
+		value interface{}
+	}{
+		{"pid", os.Getpid()},
+		{"uptime", int64(now.Sub(s.started).Seconds())},
+		{"time", now.Unix()},
+		{"version", Version},
+		{"curr_connections", s.tcp.Conns()},
+		{"total_connections", s.totalConns.Load()},
+		{"cmd_get", s.cmdGet.Load()},
+		{"cmd_set", s.cmdSet.Load()},
+		{"get_hits", s.getHits.Load()},
+		{"get_misses", s.getMisses.Load()},
+		{"curr_items", s.cache.Len()},
+		{"evictions", s.cache.Evictions()},
+	}
+	for _, st := range stats {
+		fmt.Fprintf(w, "STAT %s %v\r\n", st.name, st.value)
+	}
+	w.WriteString("END\r\n")
+}
+
+// checkKey validates a key against the memcached key rules.
+func checkKey(key string) error {
+	if len(key) > maxKeyLen {
+		return &clientError{msg: "key too long"}
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return &clientError{msg: "invalid key"}
+		}
+	}
+	return nil
+}
+
+// expiration converts a memcached exptime into a cache TTL. Values up to 30 days are relative
+// seconds, larger values are Unix timestamps and negative values mean already expired.
+// Timestamps too far ahead for a time.Duration never expire.
+func expiration(exptime int64) (time.Duration, bool) {
+	switch {
+	case exptime == 0:
+		return cache.NoExpiration, false
+	case exptime < 0:
+		return 0, true
+	case exptime <= relativeLimit:
+		return time.Duration(exptime) * time.Second, false
+	}
+	secs := exptime - time.Now().Unix()
+	switch {
+	case secs <= 0:
+		return 0, true
+	case secs > math.MaxInt64/int64(time.Second):
+		return cache.NoExpiration, false
+	}
+	return time.Duration(secs) * time.Second, false
+}
+
+// encodeValue builds the cache value for data written with the given client flags.
+func encodeValue(flags uint32, data []byte) interface{} {
+	if flags == 0 {
+		return data
+	}
+	return Item{Flags: flags, Value: data}
+}
+
+// decodeValue returns the client flags and bytes for a cached value. Values written by Go
+// code are served when they have a natural byte form; it reports false for other types.
+func decodeValue(v interface{}) (uint32, []byte, bool) {
+	switch x := v.(type) {
+	case Item:
+		return x.Flags, x.Value, true
+	case []byte:
+		return 0, x, true
+	case string:
+		return 0, []byte(x), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return 0, []byte(fmt.Sprint(x)), true
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,213 @@
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+func startServer(t *testing.T) (*cache.Cache, net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	c := cache.New()
+	s := NewServer(c)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go s.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		s.Close()
+		c.Close()
+	})
+	return c, conn, bufio.NewReader(conn)
+}
+
+// roundTrip sends a request and reads reply lines until one of the terminators is seen.
+func roundTrip(t *testing.T, conn net.Conn, r *bufio.Reader, req string) string {
+	t.Helper()
+
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var reply strings.Builder
+	afterValue := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading reply to %q: %v", req, err)
+		}
+		reply.WriteString(line)
+
+		isValue := strings.HasPrefix(line, "VALUE ")
+		if !afterValue && !isValue && !strings.HasPrefix(line, "STAT ") {
+			return reply.String()
+		}
+		afterValue = isValue
+	}
+}
+
+func TestStorageCommands(t *testing.T) {
+	_, conn, r := startServer(t)
+
+	testCases := []struct {
+		req  string
+		want string
+	}{
+		{"get missing\r\n", "END\r\n"},
+		{"set a 0 0 5\r\nhello\r\n", "STORED\r\n"},
+		{"get a\r\n", "VALUE a 0 5\r\nhello\r\nEND\r\n"},
+		{"set b 42 0 2\r\nhi\r\n", "STORED\r\n"},
+		{"get a b missing\r\n", "VALUE a 0 5\r\nhello\r\nVALUE b 42 2\r\nhi\r\nEND\r\n"},
+		{"add a 0 0 1\r\nx\r\n", "NOT_STORED\r\n"},
+		{"add c 0 0 1\r\nx\r\n", "STORED\r\n"},
+		{"replace missing 0 0 1\r\nx\r\n", "NOT_STORED\r\n"},
+		{"replace c 0 0 1\r\ny\r\n", "STORED\r\n"},
+		{"set n 0 0 2\r\n10\r\n", "STORED\r\n"},
+		{"incr n 5\r\n", "15\r\n"},
+		{"decr n 20\r\n", "0\r\n"},
+		{"incr a 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+		{"incr missing 1\r\n", "NOT_FOUND\r\n"},
+		{"delete c\r\n", "DELETED\r\n"},
+		{"delete c\r\n", "NOT_FOUND\r\n"},
+		{"set quiet 0 0 1 noreply\r\nq\r\nget quiet\r\n", "VALUE quiet 0 1\r\nq\r\nEND\r\n"},
+		{"set gone 0 -1 1\r\nx\r\n", "STORED\r\n"},
+		{"get gone\r\n", "END\r\n"},
+		{"bogus\r\n", "ERROR\r\n"},
+		{"flush_all\r\n", "OK\r\n"},
+		{"get a b n\r\n", "END\r\n"},
+	}
+
+	for _, tc := range testCases {
+		if got := roundTrip(t, conn, r, tc.req); got != tc.want {
+			t.Errorf("%q = %q, want %q", tc.req, got, tc.want)
+		}
+	}
+}
+
+func TestCAS(t *testing.T) {
+	c, conn, r := startServer(t)
+
+	roundTrip(t, conn, r, "set k 0 0 1\r\na\r\n")
+	_, version, _ := c.GetWithVersion("k")
+
+	reply := roundTrip(t, conn, r, "gets k\r\n")
+	wantPrefix := "VALUE k 0 1 "
+	if !strings.HasPrefix(reply, wantPrefix) {
+		t.Fatalf("gets k = %q, want prefix %q", reply, wantPrefix)
+	}
+
+	unique := strings.Fields(strings.SplitN(reply, "\r\n", 2)[0])[4]
+	if got := roundTrip(t, conn, r, "cas k 0 0 1 "+unique+"\r\nb\r\n"); got != "STORED\r\n" {
+		t.Errorf("cas with current unique = %q, want STORED", got)
+	}
+	if got := roundTrip(t, conn, r, "cas k 0 0 1 "+unique+"\r\nc\r\n"); got != "EXISTS\r\n" {
+		t.Errorf("cas with stale unique = %q, want EXISTS", got)
+	}
+	if got := roundTrip(t, conn, r, "cas nope 0 0 1 1\r\nc\r\n"); got != "NOT_FOUND\r\n" {
+		t.Errorf("cas on missing key = %q, want NOT_FOUND", got)
+	}
+
+	if _, newVersion, _ := c.GetWithVersion("k"); newVersion == version {
+		t.Errorf("version unchanged after cas, want a new CAS unique")
+	}
+}
+
+func TestStats(t *testing.T) {
+	_, conn, r := startServer(t)
+
+	roundTrip(t, conn, r, "set a 0 0 1\r\nx\r\n")
+	roundTrip(t, conn, r, "get a missing\r\n")
+
+	reply := roundTrip(t, conn, r, "stats\r\n")
+	for _, want := range []string{"STAT get_hits 1\r\n", "STAT get_misses 1\r\n", "STAT curr_items 1\r\n", "STAT cmd_set 1\r\n"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("stats reply missing %q:\n%s", want, reply)
+		}
+	}
+	if !strings.HasSuffix(reply, "END\r\n") {
+		t.Errorf("stats reply not terminated by END:\n%s", reply)
+	}
+}
+
+func TestOversizedValueIsRejectedBeforeReading(t *testing.T) {
+	testCases := []string{
+		"set k 0 0 9223372036854775807\r\n",
+		"set k 0 0 1099511627776\r\n",
+		"set k 0 0 1048577\r\n",
+	}
+
+	for _, req := range testCases {
+		_, conn, r := startServer(t)
+		if got, want := roundTrip(t, conn, r, req), "SERVER_ERROR object too large for cache\r\n"; got != want {
+			t.Errorf("%q = %q, want %q", req, got, want)
+		}
+		if _, err := r.ReadString('\n'); err == nil {
+			t.Errorf("%q left the connection open", req)
+		}
+	}
+}
+
+func TestFlagsSurviveSnapshot(t *testing.T) {
+	c, conn, r := startServer(t)
+	roundTrip(t, conn, r, "set k 5 0 2\r\nhi\r\n")
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	restored := cache.New()
+	defer restored.Close()
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if v, ok := restored.Get("k"); !ok || !reflect.DeepEqual(v, Item{Flags: 5, Value: []byte("hi")}) {
+		t.Errorf("Get(k) after Load = %v, %v, want the item with its flags", v, ok)
+	}
+}
+
+func TestLineTooLong(t *testing.T) {
+	_, conn, r := startServer(t)
+
+	req := "get " + strings.Repeat("k", maxLineLen) + "\r\n"
+	if got, want := roundTrip(t, conn, r, req), "CLIENT_ERROR line too long\r\n"; got != want {
+		t.Errorf("long line = %q, want %q", got, want)
+	}
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Errorf("long line left the connection open")
+	}
+}
+
+func TestDelayedFlushAll(t *testing.T) {
+	c, conn, r := startServer(t)
+
+	roundTrip(t, conn, r, "set a 0 0 1\r\nx\r\n")
+	future := strconv.FormatInt(time.Now().Unix()+3600, 10)
+	for _, delay := range []string{"3600", future, "9223372036854775807"} {
+		if got := roundTrip(t, conn, r, "flush_all "+delay+"\r\n"); got != "OK\r\n" {
+			t.Errorf("flush_all %s = %q, want OK", delay, got)
+		}
+		if _, ok := c.Get("a"); !ok {
+			t.Errorf("flush_all %s cleared the cache immediately", delay)
+		}
+	}
+	if got := roundTrip(t, conn, r, "flush_all 0\r\n"); got != "OK\r\n" {
+		t.Errorf("flush_all 0 = %q, want OK", got)
+	}
+	if got := roundTrip(t, conn, r, "get a\r\n"); got != "END\r\n" {
+		t.Errorf("get a after flush_all 0 = %q, want END", got)
+	}
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+)
+
+// StatsSource is implemented by caches that report Stats, such as TypedCache, Cache and Sharded.
+type StatsSource interface {
+	Stats() Stats
+}
+
+// metric describes one exported series.
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(Stats) uint64
+}
+
+var metrics = []metric{
+	{"cache_hits_total", "counter", "Lookups that found a live entry.", func(s Stats) uint64 { return s.Hits }},
+	{"cache_misses_total", "counter", "Lookups that found no live entry.", func(s Stats) uint64 { return s.Misses }},
+	{"cache_sets_total", "counter", "Values written to the cache.", func(s Stats) uint64 { return s.Sets }},
+	{"cache_deletes_total", "counter", "Entries removed explicitly.", func(s Stats) uint64 { return s.Deletes }},
+	{"cache_evictions_total", "counter", "Entries evicted to respect the capacity.", func(s Stats) uint64 { return s.Evictions }},
+	{"cache_expirations_total", "counter", "Expired entries removed or overwritten.", func(s Stats) uint64 { return s.Expirations }},
+	{"cache_rejections_total", "counter", "New values not stored by the admission policy.", func(s Stats) uint64 { return s.Rejections }},
+	{"cache_entries", "gauge", "Entries currently stored.", func(s Stats) uint64 { return uint64(s.Size) }},
+	{"cache_cost", "gauge", "Total cost of the entries currently stored.", func(s Stats) uint64 { return uint64(s.Cost) }},
+}
+
+// MetricsHandler returns an http.Handler that serves the statistics of src in the Prometheus
+// text exposition format, ready to be scraped at a path such as /metrics.
+func MetricsHandler(src StatsSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := src.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		for _, m := range metrics {
+			fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", m.name, m.help, m.name, m.kind, m.name, m.value(s))
+		}
+		bw.Flush()
+	})
+}
@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyncPolicy controls how often the operation log is flushed to stable storage with fsync.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs after every logged operation. It is the most durable and the slowest.
+	SyncAlways SyncPolicy = iota
+	// SyncEverySecond fsyncs once per second, losing at most about a second of writes on a crash.
+	SyncEverySecond
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// minCompactRecords is the smallest number of appended records that triggers automatic compaction.
+const minCompactRecords = 1024
+
+// ErrLogOpen is returned by OpenLog when the cache already has an operation log attached.
+var ErrLogOpen = errors.New("cache: operation log already open")
+
+// logOp identifies the kind of a logged operation.
+type logOp uint8
+
+const (
+	opSet logOp = iota + 1
+	opDelete
+	opClear
+)
+
+// logRecord is a single operation in the log. A zero ExpiresAt means the entry never expires.
+type logRecord[K comparable, V any] struct {
+	Op        logOp
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+	Tags      []string
+	Cost      int64
+}
+
+// opLog is an append-only file recording every Set and Delete applied to a cache.
+type opLog[K comparable, V any] struct {
+	mu         sync.Mutex
+	path       string
+	policy     SyncPolicy
+	file       *os.File
+	buf        *bufio.Writer
+	enc        *gob.Encoder
+	syncer     *janitor
+	appended   int
+	compacting atomic.Bool
+}
+
+// OpenLog replays the operation log at path into the cache and then records every subsequent
+// Set and Delete to it using the given fsync policy. A missing file is created. A record torn
+// by a crash at the end of the file is discarded. The log is compacted on open and again
+// whenever it has grown to about twice the number of live entries; CompactLog forces it.
+// Values stored as interface{} must have their concrete types registered with gob.Register.
+func (c *TypedCache[K, V]) OpenLog(path string, policy SyncPolicy) error {
+	// The lock is held from the replay until the log is installed, so that no write can land
+	// between the compaction and the first append and be missing from the log.
+	c.mu.Lock()
+	defer c.unlock()
+	if c.oplog != nil {
+		return ErrLogOpen
+	}
+	if err := c.replayLog(path); err != nil {
+		return err
+	}
+
+	l := &opLog[K, V]{path: path, policy: policy}
+	if err := l.rewrite(c); err != nil {
+		return err
+	}
+
+	if policy == SyncEverySecond {
+		l.syncer = newJanitor(time.Second)
+		go l.syncer.run(func() {
+			if err := l.sync(); err != nil {
+				c.errorHandler(fmt.Errorf("cache: sync log %s: %w", path, err))
+			}
+		})
+	}
+	c.oplog = l
+	return nil
+}
+
+// CompactLog rewrites the operation log so that it holds one Set per live entry.
+// The new log is written to a temporary file and renamed over the old one.
+func (c *TypedCache[K, V]) CompactLog() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.oplog == nil {
+		return nil
+	}
+	return c.oplog.rewrite(c)
+}
+
+// replayLog applies the records stored at path. The caller must hold c.mu.
+func (c *TypedCache[K, V]) replayLog(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	now := c.clock.Now()
+	for {
+		var rec logRecord[K, V]
+		err := dec.Decode(&rec)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cache: replay log %s: %w", path, err)
+		}
+
+		switch {
+		case rec.Op == opClear:
+			c.clear()
+		case rec.Op == opSet && (rec.ExpiresAt.IsZero() || now.Before(rec.ExpiresAt)):
+			c.put(rec.Key, rec.Value, rec.ExpiresAt, rec.Tags, rec.Cost)
+		default:
+			if e, ok := c.store[rec.Key]; ok {
+				c.removeEntry(e, ReasonDeleted)
+			}
+		}
+	}
+}
+
+// logSet records a Set. The caller must hold c.mu.
+func (c *TypedCache[K, V]) logSet(e *entry[K, V]) {
+	if c.oplog != nil {
+		c.appendLog(logRecord[K, V]{Op: opSet, Key: e.key, Value: e.value, ExpiresAt: e.expiresAt, Tags: e.tags, Cost: e.cost})
+	}
+}
+
+// logDelete records a Delete or eviction. The caller must hold c.mu.
+func (c *TypedCache[K, V]) logDelete(key K) {
+	if c.oplog != nil {
+		c.appendLog(logRecord[K, V]{Op: opDelete, Key: key})
+	}
+}
+
+// logClear records a Clear. The caller must hold c.mu.
+func (c *TypedCache[K, V]) logClear() {
+	if c.oplog != nil {
+		c.appendLog(logRecord[K, V]{Op: opClear})
+	}
+}
+
+// appendLog writes the record and schedules a compaction once the log has grown too large.
+func (c *TypedCache[K, V]) appendLog(rec logRecord[K, V]) {
+	l := c.oplog
+	appended, err := l.append(&rec)
+	if err != nil {
+		c.errorHandler(fmt.Errorf("cache: append log %s: %w", l.path, err))
+		return
+	}
+
+	if appended >= minCompactRecords && appended > 2*len(c.store) && l.compacting.CompareAndSwap(false, true) {
+		go func() {
+			defer l.compacting.Store(false)
+			if err := c.CompactLog(); err != nil {
+				c.errorHandler(fmt.Errorf("cache: compact log %s: %w", l.path, err))
+			}
+		}()
+	}
+}
+
+// append encodes the record and returns the number of records appended since the last rewrite.
+func (l *opLog[K, V]) append(rec *logRecord[K, V]) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(rec); err != nil {
+		return l.appended, err
+	}
+	if err := l.buf.Flush(); err != nil {
+		return l.appended, err
+	}
+	l.appended++
+	if l.policy == SyncAlways {
+		return l.appended, l.file.Sync()
+	}
+	return l.appended, nil
+}
+
+// rewrite replaces the log file with one Set record per live entry of c.
+// The caller must hold c.mu, for reading or writing, so that no operations are appended meanwhile.
+func (l *opLog[K, V]) rewrite(c *TypedCache[K, V]) error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(tmp)
+	enc := gob.NewEncoder(buf)
+	err = func() error {
+		now := c.clock.Now()
+		for e := c.lru.back(); e != nil; e = e.prev {
+			if e.expired(now) {
+				continue
+			}
+			rec := logRecord[K, V]{Op: opSet, Key: e.key, Value: e.value, ExpiresAt: e.expiresAt, Tags: e.tags, Cost: e.cost}
+			if err := enc.Encode(&rec); err != nil {
+				return err
+			}
+		}
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if err := tmp.Sync(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), l.path)
+	}()
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file, l.buf, l.enc = tmp, buf, enc
+	l.appended = 0
+	return nil
+}
+
+// sync flushes the log file to stable storage.
+func (l *opLog[K, V]) sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Sync()
+}
+
+// close stops the background syncer and syncs and closes the log file.
+func (l *opLog[K, V]) close() error {
+	if l.syncer != nil {
+		l.syncer.stop()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
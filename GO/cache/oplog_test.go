@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestOpLogReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	for _, policy := range []SyncPolicy{SyncAlways, SyncEverySecond, SyncNever} {
+		t.Run("policy="+strconv.Itoa(int(policy)), func(t *testing.T) {
+			os.Remove(path)
+
+			c := NewTyped[string, int]()
+			if err := c.OpenLog(path, policy); err != nil {
+				t.Fatalf("OpenLog() error = %v", err)
+			}
+			if err := c.OpenLog(path, policy); !errors.Is(err, ErrLogOpen) {
+				t.Errorf("second OpenLog() error = %v, want ErrLogOpen", err)
+			}
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Set("a", 3)
+			c.Delete("b")
+			c.Close()
+
+			restored := NewTyped[string, int]()
+			defer restored.Close()
+			if err := restored.OpenLog(path, policy); err != nil {
+				t.Fatalf("OpenLog() replay error = %v", err)
+			}
+			if v, ok := restored.Get("a"); !ok || v != 3 {
+				t.Errorf("Get(a) = %v, %v, want 3, true", v, ok)
+			}
+			if _, ok := restored.Get("b"); ok {
+				t.Errorf("Get(b) hit, want deleted key to stay deleted")
+			}
+		})
+	}
+}
+
+func TestOpLogTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c := NewTyped[string, string]()
+	if err := c.OpenLog(path, SyncNever); err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	c.Set("a", "first")
+	c.Set("b", "second")
+	c.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+
+	restored := NewTyped[string, string]()
+	defer restored.Close()
+	if err := restored.OpenLog(path, SyncNever); err != nil {
+		t.Fatalf("OpenLog() error = %v, want torn record to be discarded", err)
+	}
+	if v, ok := restored.Get("a"); !ok || v != "first" {
+		t.Errorf("Get(a) = %v, %v, want first, true", v, ok)
+	}
+	if _, ok := restored.Get("b"); ok {
+		t.Errorf("Get(b) hit, want torn record discarded")
+	}
+}
+
+func TestCompactLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	c := NewTyped[string, int]()
+	defer c.Close()
+	if err := c.OpenLog(path, SyncNever); err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		c.Set("counter", i)
+	}
+
+	before, _ := os.Stat(path)
+	if err := c.CompactLog(); err != nil {
+		t.Fatalf("CompactLog() error = %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("log size after compaction = %d, want less than %d", after.Size(), before.Size())
+	}
+
+	c.Set("other", 1)
+	c.Close()
+
+	restored := NewTyped[string, int]()
+	defer restored.Close()
+	if err := restored.OpenLog(path, SyncNever); err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	if v, ok := restored.Get("counter"); !ok || v != 499 {
+		t.Errorf("Get(counter) = %v, %v, want 499, true", v, ok)
+	}
+	if v, ok := restored.Get("other"); !ok || v != 1 {
+		t.Errorf("Get(other) = %v, %v, want 1, true", v, ok)
+	}
+}
+
+func TestOpenLogDuringWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.log")
+	c := NewTyped[int, int]()
+
+	stop := make(chan struct{})
+	written := make(chan int)
+	go func() {
+		n := 0
+		defer func() { written <- n }()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.Set(n, n)
+				n++
+			}
+		}
+	}()
+	if err := c.OpenLog(path, SyncNever); err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	close(stop)
+	n := <-written
+	c.Close()
+
+	restored := NewTyped[int, int]()
+	defer restored.Close()
+	if err := restored.OpenLog(path, SyncNever); err != nil {
+		t.Fatalf("OpenLog() replay error = %v", err)
+	}
+	if got := restored.Len(); got != n {
+		t.Errorf("replayed %d entries, want all %d written while the log was opened", got, n)
+	}
+}
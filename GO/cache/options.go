@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"log"
+	"time"
+)
+
+// Option configures a cache created by New, NewTyped or NewSharded.
+type Option func(*options)
+
+type options struct {
+	defaultTTL      time.Duration
+	cleanupInterval time.Duration
+	clock           Clock
+	maxEntries      int
+	maxCost         int64
+	costFunc        any
+	tinyLFU         bool
+	keyIndex        bool
+	onEvict         any
+	negativeTTL     time.Duration
+	errorTTL        time.Duration
+	refreshAhead    time.Duration
+	staleGrace      time.Duration
+
+	store               any
+	writeBehindInterval time.Duration
+	writeBehindRetries  int
+
+	snapshotPath     string
+	snapshotInterval time.Duration
+	errorHandler     func(error)
+}
+
+// newOptions applies opts on top of the defaults.
+func newOptions(opts []Option) options {
+	o := options{
+		defaultTTL:   NoExpiration,
+		clock:        systemClock{},
+		errorHandler: func(err error) { log.Printf("cache background error: %v", err) },
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDefaultTTL sets the TTL applied by Set and by SetWithTTL with DefaultExpiration.
+// A non-positive ttl means entries never expire by default.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl <= 0 {
+			ttl = NoExpiration
+		}
+		o.defaultTTL = ttl
+	}
+}
+
+// WithCleanupInterval starts a background janitor that removes expired entries every interval.
+// A non-positive interval disables the janitor; expired entries are then only hidden from Get
+// until they are overwritten, deleted or removed with DeleteExpired.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cleanupInterval = interval
+	}
+}
+
+// WithClock replaces the time source used for expiration, which is mainly useful in tests.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+// WithMaxEntries bounds the cache to at most n entries. When a new key would exceed the limit,
+// the least recently used entry is evicted. A non-positive n leaves the cache unbounded.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithMaxCost bounds the total cost of the stored entries to max, evicting least recently used
+// entries until a write fits. Each entry costs what was passed to WithCost when it was written,
+// otherwise what the WithCostFunc function returns, otherwise 1. A value whose cost alone
+// exceeds max is not stored. A non-positive max leaves the cost unbounded.
+func WithMaxCost(max int64) Option {
+	return func(o *options) {
+		o.maxCost = max
+	}
+}
+
+// WithCostFunc sets the function computing the cost of entries written without WithCost,
+// typically their size in bytes. The key and value types must match the cache's, otherwise
+// NewTyped panics. A negative cost counts as 0.
+func WithCostFunc[K comparable, V any](fn func(key K, value V) int64) Option {
+	return func(o *options) {
+		o.costFunc = fn
+	}
+}
+
+// WithTinyLFU adds a TinyLFU admission policy to a cache bounded by WithMaxEntries or
+// WithMaxCost. The cache keeps a compact estimate of how often each key was looked up recently,
+// and a new key that would require evictions is only stored if none of the entries it would
+// evict is used more often. This keeps one-off keys, such as those of a full scan, from
+// flushing out the frequently used ones. Rejected values are reported as ReasonRejected.
+// It has no effect on an unbounded cache.
+func WithTinyLFU() Option {
+	return func(o *options) {
+		o.tinyLFU = true
+	}
+}
+
+// WithNegativeTTL makes GetOrLoad remember loader results that report ErrNotFound for ttl,
+// so repeated lookups of missing keys do not reach the loader. Disabled by default.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithErrorTTL makes GetOrLoad remember other loader errors for ttl instead of retrying
+// the loader on the next call. Disabled by default.
+func WithErrorTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.errorTTL = ttl
+	}
+}
+
+// WithRefreshAhead makes GetOrLoad and Fetch refresh a loaded entry in the background when
+// it is read less than window before it expires, so that frequently read keys never expire
+// and callers never wait for the loader. The current value is returned meanwhile. At most one
+// load of a key runs at a time. Disabled by default.
+func WithRefreshAhead(window time.Duration) Option {
+	return func(o *options) {
+		o.refreshAhead = window
+	}
+}
+
+// WithStaleWhileRevalidate lets GetOrLoad and Fetch keep returning a loaded entry for grace
+// after it expired while a single background load refreshes it. Other methods, such as Get,
+// treat the entry as expired, but the janitor keeps it until the grace period ends.
+// Refresh failures are reported to the error handler. Disabled by default.
+func WithStaleWhileRevalidate(grace time.Duration) Option {
+	return func(o *options) {
+		o.staleGrace = grace
+	}
+}
+
+// WithSnapshot writes the cache contents to path every interval and once more on Close.
+// Each snapshot is written to a temporary file and renamed over path, so a crash never
+// leaves a torn file behind. Restore it on startup with LoadFile.
+// It is not supported by NewSharded, which panics if it is given.
+func WithSnapshot(path string, interval time.Duration) Option {
+	return func(o *options) {
+		o.snapshotPath = path
+		o.snapshotInterval = interval
+	}
+}
+
+// WithErrorHandler sets the function that receives errors from background work such as
+// auto-snapshots. By default errors are written with the standard log package.
+func WithErrorHandler(handler func(error)) Option {
+	return func(o *options) {
+		if handler != nil {
+			o.errorHandler = handler
+		}
+	}
+}
+
+// WithKeyIndex maintains the keys in sorted order so that prefix operations such as
+// DeletePrefix seek directly to the matching keys instead of scanning the whole cache.
+// It costs O(log n) per insert and delete and requires a built-in string or numeric key type.
+func WithKeyIndex() Option {
+	return func(o *options) {
+		o.keyIndex = true
+	}
+}
+
+// SetOption configures a single entry written with SetWith.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	ttl  time.Duration
+	tags []string
+	cost int64
+}
+
+// WithTTL sets the entry's TTL. DefaultExpiration, NoExpiration and KeepTTL are accepted.
+func WithTTL(ttl time.Duration) SetOption {
+	return func(o *setOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithCost sets the entry's cost counted against WithMaxCost instead of computing it with the
+// cost function. A non-positive cost is ignored.
+func WithCost(cost int64) SetOption {
+	return func(o *setOptions) {
+		o.cost = cost
+	}
+}
+
+// WithTags attaches tags to the entry so that it can be removed with InvalidateTag.
+// They replace any tags the entry had before.
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
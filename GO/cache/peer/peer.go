@@ -0,0 +1,347 @@
+// Package peer spreads a cache over a group of processes, in the style of groupcache.
+//
+// Every key is owned by one peer, chosen by consistent hashing over the group's members.
+// The owner loads missing keys with the Getter and keeps them in its local cache; the other
+// peers fetch them from the owner over HTTP and keep only the most frequently requested ones
+// in a small hot-key mirror, so that hot keys do not all land on a single process. Each
+// process serves its owned keys by mounting its Group as an HTTP handler:
+//
+//	g := peer.NewGroup("http://10.0.0.1:8080", cache.New(cache.WithMaxEntries(100_000)), getter)
+//	g.SetPeers("http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://10.0.0.3:8080")
+//	http.Handle(peer.DefaultBasePath, g)
+//
+// Values are byte slices and must not be modified after they are returned or stored.
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// Defaults for the options.
+const (
+	DefaultReplicas   = 50
+	DefaultBasePath   = "/_peer/"
+	DefaultHotEntries = 1024
+	DefaultHotTTL     = time.Minute
+)
+
+// maxValueBytes bounds the size of a value fetched from a peer. It is a variable for tests.
+var maxValueBytes int64 = 64 << 20
+
+// Getter loads the value for a key owned by this process. It can return cache.ErrNotFound
+// to report a missing key.
+type Getter func(ctx context.Context, key string) ([]byte, error)
+
+// Option configures a Group.
+type Option func(*config)
+
+type config struct {
+	replicas     int
+	basePath     string
+	hotEntries   int
+	hotTTL       time.Duration
+	client       *http.Client
+	errorHandler func(error)
+}
+
+// WithReplicas sets how many virtual nodes each peer has on the hash ring. More virtual
+// nodes spread the keys more evenly at the cost of a larger ring. All peers must use the
+// same value.
+func WithReplicas(n int) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.replicas = n
+		}
+	}
+}
+
+// WithBasePath sets the URL path under which peers serve each other, DefaultBasePath by
+// default. All peers must use the same path.
+func WithBasePath(path string) Option {
+	return func(cfg *config) {
+		if path != "" {
+			cfg.basePath = path
+		}
+	}
+}
+
+// WithHotEntries sets how many keys owned by other peers are mirrored locally.
+// Zero disables the mirror.
+func WithHotEntries(n int) Option {
+	return func(cfg *config) {
+		cfg.hotEntries = max(n, 0)
+	}
+}
+
+// WithHotTTL sets how long a mirrored value is served before it is fetched from its owner
+// again, which bounds how stale a mirrored value can be.
+func WithHotTTL(ttl time.Duration) Option {
+	return func(cfg *config) {
+		if ttl > 0 {
+			cfg.hotTTL = ttl
+		}
+	}
+}
+
+// WithHTTPClient sets the client used to fetch values from peers, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(cfg *config) {
+		if client != nil {
+			cfg.client = client
+		}
+	}
+}
+
+// WithErrorHandler sets the function receiving errors from failed peer fetches, which are
+// otherwise written with the standard log package.
+func WithErrorHandler(handler func(error)) Option {
+	return func(cfg *config) {
+		if handler != nil {
+			cfg.errorHandler = handler
+		}
+	}
+}
+
+// Stats holds a Group's counters.
+type Stats struct {
+	Gets        uint64 // calls to Get
+	LocalLoads  uint64 // Getter calls for keys this process owns
+	PeerFetches uint64 // values fetched from their owners
+	PeerErrors  uint64 // failed fetches, served by a local Getter call instead
+	HotHits     uint64 // Gets served by the hot-key mirror
+	ServedPeers uint64 // requests served to other peers
+}
+
+type counters struct {
+	gets, localLoads, peerFetches, peerErrors, hotHits, servedPeers atomic.Uint64
+}
+
+// Group is one process's member of a peer group. It is safe for concurrent use.
+type Group struct {
+	self   string
+	local  *cache.Cache
+	hot    *cache.Cache
+	getter Getter
+	ring   *Ring
+	cfg    config
+	stats  counters
+
+	mu    sync.Mutex
+	calls map[string]*call // fetches from peers in flight
+}
+
+// call is a fetch from a peer shared by every Get of the same key.
+type call struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// NewGroup creates the group member for this process. self is the base URL other peers use
+// to reach it, such as "http://10.0.0.1:8080". local holds the keys this process owns and
+// getter loads them. The group starts with self as its only peer; call SetPeers to add the
+// others. The caller remains responsible for closing local.
+func NewGroup(self string, local *cache.Cache, getter Getter, opts ...Option) *Group {
+	cfg := config{
+		replicas:     DefaultReplicas,
+		basePath:     DefaultBasePath,
+		hotEntries:   DefaultHotEntries,
+		hotTTL:       DefaultHotTTL,
+		client:       http.DefaultClient,
+		errorHandler: func(err error) { log.Printf("peer: %v", err) },
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	g := &Group{self: self, local: local, getter: getter, ring: NewRing(cfg.replicas, self), cfg: cfg}
+	if cfg.hotEntries > 0 {
+		// TinyLFU admission keeps rarely requested keys from displacing the hot ones.
+		g.hot = cache.New(cache.WithMaxEntries(cfg.hotEntries), cache.WithTinyLFU(), cache.WithDefaultTTL(cfg.hotTTL))
+	}
+	return g
+}
+
+// SetPeers replaces the group's members. peers are base URLs like self and should include
+// self, otherwise this process owns no keys. It can be called at any time; the hot-key
+// mirror is cleared because the owners of its keys may have changed.
+func (g *Group) SetPeers(peers ...string) {
+	g.ring.Set(peers...)
+	if g.hot != nil {
+		g.hot.Clear()
+	}
+}
+
+// Peers returns the group's members in sorted order.
+func (g *Group) Peers() []string {
+	return g.ring.Peers()
+}
+
+// Owner returns the peer owning the key.
+func (g *Group) Owner(key string) string {
+	return g.ring.Get(key)
+}
+
+// Get returns the value for the key. Keys owned by this process are served from the local
+// cache and loaded with the Getter on a miss. Other keys are served from the hot-key mirror
+// or fetched from their owner; if the owner cannot be reached the value is loaded with the
+// Getter without being cached.
+func (g *Group) Get(ctx context.Context, key string) ([]byte, error) {
+	g.stats.gets.Add(1)
+	owner := g.ring.Get(key)
+	if owner == "" || owner == g.self {
+		return g.load(ctx, key)
+	}
+
+	if g.hot != nil {
+		if v, ok := g.hot.Get(key); ok {
+			g.stats.hotHits.Add(1)
+			return v.([]byte), nil
+		}
+	}
+
+	g.mu.Lock()
+	cl, ok := g.calls[key]
+	if !ok {
+		cl = &call{done: make(chan struct{})}
+		if g.calls == nil {
+			g.calls = make(map[string]*call)
+		}
+		g.calls[key] = cl
+		go g.fetch(context.WithoutCancel(ctx), owner, key, cl)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load returns the value of an owned key from the local cache, calling the Getter on a miss.
+func (g *Group) load(ctx context.Context, key string) ([]byte, error) {
+	v, err := g.local.GetOrLoad(ctx, key, func(ctx context.Context, key string) (interface{}, error) {
+		g.stats.localLoads.Add(1)
+		return g.getter(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// fetch gets the key from its owner, mirrors the value and wakes the waiting callers.
+func (g *Group) fetch(ctx context.Context, owner, key string, cl *call) {
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(cl.done)
+	}()
+
+	g.stats.peerFetches.Add(1)
+	cl.value, cl.err = g.fetchFrom(ctx, owner, key)
+	switch {
+	case cl.err == nil:
+		if g.hot != nil {
+			g.hot.Set(key, cl.value)
+		}
+	case !errors.Is(cl.err, cache.ErrNotFound):
+		g.stats.peerErrors.Add(1)
+		g.cfg.errorHandler(cl.err)
+		g.stats.localLoads.Add(1)
+		cl.value, cl.err = g.getter(ctx, key)
+	}
+}
+
+// fetchFrom requests the key from a peer. A 404 response is reported as cache.ErrNotFound.
+func (g *Group) fetchFrom(ctx context.Context, peer, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+g.cfg.basePath+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %q from %s: %w", key, peer, err)
+	}
+	resp, err := g.cfg.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %q from %s: %w", key, peer, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxValueBytes+1))
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("fetching %q from %s: %w", key, peer, err)
+	case int64(len(body)) > maxValueBytes:
+		return nil, fmt.Errorf("fetching %q from %s: value larger than %d bytes", key, peer, maxValueBytes)
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, cache.ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetching %q from %s: %s: %s", key, peer, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// ServeHTTP serves other peers' requests for keys under the base path. It always answers
+// from the local cache and Getter, even for keys this process does not own according to
+// its own view of the membership, so requests never bounce between peers.
+func (g *Group) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	escaped, ok := strings.CutPrefix(r.URL.EscapedPath(), g.cfg.basePath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		http.Error(w, "bad key", http.StatusBadRequest)
+		return
+	}
+
+	g.stats.servedPeers.Add(1)
+	v, err := g.load(r.Context(), key)
+	switch {
+	case errors.Is(err, cache.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(v)
+	}
+}
+
+// Stats returns the group's counters.
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:        g.stats.gets.Load(),
+		LocalLoads:  g.stats.localLoads.Load(),
+		PeerFetches: g.stats.peerFetches.Load(),
+		PeerErrors:  g.stats.peerErrors.Load(),
+		HotHits:     g.stats.hotHits.Load(),
+		ServedPeers: g.stats.servedPeers.Load(),
+	}
+}
+
+// Close releases the hot-key mirror. It does not close the local cache.
+func (g *Group) Close() {
+	if g.hot != nil {
+		g.hot.Close()
+	}
+}
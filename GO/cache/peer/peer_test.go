@@ -0,0 +1,225 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// node is one process of a test cluster.
+type node struct {
+	group *Group
+	srv   *httptest.Server
+	loads atomic.Int64
+}
+
+// newCluster starts n groups serving each other over HTTP. Their Getter returns "v:" + key,
+// except for keys starting with "missing", which are reported as not found.
+func newCluster(t *testing.T, n int, opts ...Option) []*node {
+	t.Helper()
+	nodes := make([]*node, n)
+	var urls []string
+	for i := range nodes {
+		nd := &node{}
+		mux := http.NewServeMux()
+		nd.srv = httptest.NewServer(mux)
+		local := cache.New()
+		getter := func(ctx context.Context, key string) ([]byte, error) {
+			nd.loads.Add(1)
+			if strings.HasPrefix(key, "missing") {
+				return nil, cache.ErrNotFound
+			}
+			return []byte("v:" + key), nil
+		}
+		nd.group = NewGroup(nd.srv.URL, local, getter, append([]Option{WithErrorHandler(func(error) {})}, opts...)...)
+		mux.Handle(DefaultBasePath, nd.group)
+		t.Cleanup(func() {
+			nd.srv.Close()
+			nd.group.Close()
+			local.Close()
+		})
+		nodes[i] = nd
+		urls = append(urls, nd.srv.URL)
+	}
+	for _, nd := range nodes {
+		nd.group.SetPeers(urls...)
+	}
+	return nodes
+}
+
+func TestGroupLoadsEachKeyOnItsOwner(t *testing.T) {
+	nodes := newCluster(t, 3)
+	ctx := context.Background()
+
+	const keys = 60
+	for _, nd := range nodes {
+		for i := range keys {
+			key := fmt.Sprint("key/", i) // the slash must survive the URL round trip
+			v, err := nd.group.Get(ctx, key)
+			if err != nil || string(v) != "v:"+key {
+				t.Fatalf("Get(%q) = %q, %v, want %q", key, v, err, "v:"+key)
+			}
+		}
+	}
+
+	var total int64
+	for _, nd := range nodes {
+		loads := nd.loads.Load()
+		total += loads
+		if loads == 0 {
+			t.Errorf("node %s loaded no keys, want a share of them", nd.srv.URL)
+		}
+	}
+	if total != keys {
+		t.Errorf("Getter called %d times in total, want %d: each key once on its owner", total, keys)
+	}
+
+	// Keys fetched from their owners are mirrored, so a second round stays local.
+	before := nodes[0].group.Stats()
+	for i := range keys {
+		nodes[0].group.Get(ctx, fmt.Sprint("key/", i))
+	}
+	after := nodes[0].group.Stats()
+	if after.PeerFetches != before.PeerFetches {
+		t.Errorf("second round fetched %d values from peers, want 0", after.PeerFetches-before.PeerFetches)
+	}
+	if after.HotHits == before.HotHits {
+		t.Errorf("second round had no hot-key mirror hits")
+	}
+}
+
+func TestGroupNotFound(t *testing.T) {
+	nodes := newCluster(t, 2)
+	for i := range 10 {
+		key := fmt.Sprint("missing", i)
+		for _, nd := range nodes {
+			if _, err := nd.group.Get(context.Background(), key); !errors.Is(err, cache.ErrNotFound) {
+				t.Errorf("Get(%q) error = %v, want ErrNotFound", key, err)
+			}
+		}
+	}
+	for _, nd := range nodes {
+		if s := nd.group.Stats(); s.PeerErrors != 0 {
+			t.Errorf("PeerErrors = %d, want 0 for missing keys", s.PeerErrors)
+		}
+	}
+}
+
+func TestGroupConcurrentFetchesAreShared(t *testing.T) {
+	nodes := newCluster(t, 2, WithHotEntries(0))
+	g := nodes[0].group
+	var key string
+	for i := 0; ; i++ {
+		if key = fmt.Sprint("key", i); g.Owner(key) != nodes[0].srv.URL {
+			break
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.Get(context.Background(), key); err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := nodes[1].loads.Load(); got != 1 {
+		t.Errorf("owner loaded the key %d times, want 1", got)
+	}
+	if s := g.Stats(); s.HotHits != 0 {
+		t.Errorf("HotHits = %d with the mirror disabled, want 0", s.HotHits)
+	}
+}
+
+func TestGroupPeerDown(t *testing.T) {
+	var reported atomic.Int64
+	nodes := newCluster(t, 2, WithErrorHandler(func(error) { reported.Add(1) }))
+	g := nodes[0].group
+	nodes[1].srv.Close()
+
+	var key string
+	for i := 0; ; i++ {
+		if key = fmt.Sprint("key", i); g.Owner(key) == nodes[1].srv.URL {
+			break
+		}
+	}
+	v, err := g.Get(context.Background(), key)
+	if err != nil || string(v) != "v:"+key {
+		t.Fatalf("Get(%q) with its owner down = %q, %v, want a local load", key, v, err)
+	}
+	if s := g.Stats(); s.PeerErrors != 1 || reported.Load() != 1 {
+		t.Errorf("PeerErrors = %d, reported %d, want 1 and 1", s.PeerErrors, reported.Load())
+	}
+
+	// Dropping the dead peer makes this process the owner of all keys.
+	g.SetPeers(nodes[0].srv.URL)
+	if got := g.Owner(key); got != nodes[0].srv.URL {
+		t.Errorf("Owner(%q) after SetPeers = %s, want %s", key, got, nodes[0].srv.URL)
+	}
+	before := g.Stats().PeerFetches
+	g.Get(context.Background(), key)
+	if got := g.Stats().PeerFetches; got != before {
+		t.Errorf("Get() after SetPeers fetched from a peer")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	nodes := newCluster(t, 1)
+	base := nodes[0].srv.URL + DefaultBasePath
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, base + "a%2Fb", http.StatusOK},
+		{http.MethodGet, base + "missing", http.StatusNotFound},
+		{http.MethodPost, base + "a", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestGroupOversizedValue(t *testing.T) {
+	defer func(n int64) { maxValueBytes = n }(maxValueBytes)
+	maxValueBytes = 4
+
+	nodes := newCluster(t, 2)
+	g := nodes[0].group
+	var key string
+	for i := 0; ; i++ {
+		if key = fmt.Sprint("key", i); g.Owner(key) == nodes[1].srv.URL {
+			break
+		}
+	}
+
+	for range 2 {
+		v, err := g.Get(context.Background(), key)
+		if err != nil || string(v) != "v:"+key {
+			t.Fatalf("Get(%q) = %q, %v, want the full value loaded locally", key, v, err)
+		}
+	}
+	if s := g.Stats(); s.PeerErrors != 2 || s.HotHits != 0 {
+		t.Errorf("PeerErrors = %d, HotHits = %d, want 2 and 0: a truncated value must not be mirrored", s.PeerErrors, s.HotHits)
+	}
+}
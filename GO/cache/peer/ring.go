@@ -0,0 +1,98 @@
+package peer
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Ring assigns keys to peers by consistent hashing. Every peer is placed on the ring at
+// several points, its virtual nodes, and a key belongs to the peer owning the first point at
+// or after the key's hash. Adding or removing a peer therefore only moves the keys next to
+// its points. A Ring is safe for concurrent use.
+type Ring struct {
+	replicas int
+
+	mu     sync.RWMutex
+	peers  []string
+	hashes []uint64          // sorted points on the ring
+	owners map[uint64]string // peer owning each point
+}
+
+// NewRing returns a ring placing each peer at replicas points.
+func NewRing(replicas int, peers ...string) *Ring {
+	r := &Ring{replicas: max(replicas, 1)}
+	r.Set(peers...)
+	return r
+}
+
+// Set replaces the ring's peers. Duplicates are ignored.
+func (r *Ring) Set(peers ...string) {
+	peers = slices.Clone(peers)
+	slices.Sort(peers)
+	peers = slices.Compact(peers)
+
+	hashes := make([]uint64, 0, len(peers)*r.replicas)
+	owners := make(map[uint64]string, len(peers)*r.replicas)
+	for _, peer := range peers {
+		for i := range r.replicas {
+			h := hash(strconv.Itoa(i) + "#" + peer)
+			if _, taken := owners[h]; taken {
+				continue // a collision keeps the point of the peer sorting first
+			}
+			hashes = append(hashes, h)
+			owners[h] = peer
+		}
+	}
+	slices.Sort(hashes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peers, r.hashes, r.owners = peers, hashes, owners
+}
+
+// Add adds peers to the ring.
+func (r *Ring) Add(peers ...string) {
+	r.Set(append(r.Peers(), peers...)...)
+}
+
+// Remove removes peers from the ring.
+func (r *Ring) Remove(peers ...string) {
+	r.Set(slices.DeleteFunc(r.Peers(), func(p string) bool { return slices.Contains(peers, p) })...)
+}
+
+// Peers returns the ring's peers in sorted order.
+func (r *Ring) Peers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.peers)
+}
+
+// Get returns the peer owning the key, or "" if the ring is empty.
+func (r *Ring) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	i, _ := slices.BinarySearch(r.hashes, hash(key))
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// hash returns the position of s on the ring. FNV alone spreads similar strings such as the
+// virtual node names poorly, so its result is passed through the murmur3 finalizer.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
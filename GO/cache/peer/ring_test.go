@@ -0,0 +1,67 @@
+package peer
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRingEmpty(t *testing.T) {
+	r := NewRing(10)
+	if got := r.Get("a"); got != "" {
+		t.Errorf("Get() on an empty ring = %q, want empty", got)
+	}
+}
+
+func TestRingBalance(t *testing.T) {
+	peers := []string{"http://a", "http://b", "http://c", "http://d"}
+	r := NewRing(DefaultReplicas, peers...)
+
+	const keys = 100_000
+	counts := make(map[string]int)
+	for i := range keys {
+		counts[r.Get(fmt.Sprint("key", i))]++
+	}
+	for _, p := range peers {
+		share := float64(counts[p]) / keys
+		if share < 0.15 || share > 0.35 {
+			t.Errorf("peer %s owns %.2f of the keys, want about 0.25", p, share)
+		}
+	}
+}
+
+func TestRingMembershipChanges(t *testing.T) {
+	r := NewRing(DefaultReplicas, "http://a", "http://b", "http://c")
+	const keys = 10_000
+	before := make([]string, keys)
+	for i := range keys {
+		before[i] = r.Get(fmt.Sprint("key", i))
+	}
+
+	r.Add("http://d")
+	moved := 0
+	for i := range keys {
+		got := r.Get(fmt.Sprint("key", i))
+		if got != before[i] {
+			moved++
+			if got != "http://d" {
+				t.Fatalf("key%d moved from %s to %s, want only moves to the new peer", i, before[i], got)
+			}
+		}
+	}
+	if share := float64(moved) / keys; share < 0.15 || share > 0.35 {
+		t.Errorf("adding a fourth peer moved %.2f of the keys, want about 0.25", share)
+	}
+
+	r.Remove("http://d")
+	for i := range keys {
+		if got := r.Get(fmt.Sprint("key", i)); got != before[i] {
+			t.Fatalf("after removing the new peer key%d is owned by %s, want %s", i, got, before[i])
+		}
+	}
+
+	r.Set("http://b", "http://a", "http://a")
+	if got, want := r.Peers(), []string{"http://a", "http://b"}; !slices.Equal(got, want) {
+		t.Errorf("Peers() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sKrasiuk/PubRep/GO/cache/internal/glob"
+)
+
+// Message is a payload published on a channel. Pattern is the pattern of the subscription
+// that matched the channel.
+type Message struct {
+	Channel string
+	Pattern string
+	Payload interface{}
+}
+
+// ChannelSubscription receives the messages published on the channels matching any of its
+// glob patterns, such as "news.*" or "orders.[0-9]*". A message is delivered once even if
+// several patterns match. Messages are delivered on C in the order they were published;
+// like change event subscriptions, delivery never blocks the publisher: if the buffer is
+// full the message is dropped for this subscriber and counted by Dropped.
+type ChannelSubscription struct {
+	C <-chan Message
+
+	ch       chan Message
+	broker   *broker
+	patterns []string // guarded by broker.mu
+	dropped  atomic.Uint64
+}
+
+// broker routes published messages to channel subscriptions. It has its own lock, so
+// messaging does not contend with reads and writes of entries.
+type broker struct {
+	mu     sync.RWMutex
+	subs   map[*ChannelSubscription]struct{}
+	closed bool
+}
+
+// Publish sends the payload to every subscription with a pattern matching the channel and
+// returns how many received it. Subscriptions whose buffer is full miss the message.
+func (c *TypedCache[K, V]) Publish(channel string, payload interface{}) int {
+	b := &c.messages
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	received := 0
+	for sub := range b.subs {
+		i := slices.IndexFunc(sub.patterns, func(p string) bool { return glob.Match(p, channel) })
+		if i < 0 {
+			continue
+		}
+		select {
+		case sub.ch <- Message{Channel: channel, Pattern: sub.patterns[i], Payload: payload}:
+			received++
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+	return received
+}
+
+// SubscribeChannels returns a subscription to the channels matching the patterns, delivering
+// messages through a channel buffered to hold buffer messages. Patterns can be added and
+// removed later. Call Close to unsubscribe; closing the cache closes all its subscriptions.
+func (c *TypedCache[K, V]) SubscribeChannels(buffer int, patterns ...string) *ChannelSubscription {
+	ch := make(chan Message, max(buffer, 0))
+	sub := &ChannelSubscription{C: ch, ch: ch, broker: &c.messages}
+
+	b := &c.messages
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub.add(patterns)
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	if b.subs == nil {
+		b.subs = make(map[*ChannelSubscription]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Subscribe adds patterns to the subscription.
+func (s *ChannelSubscription) Subscribe(patterns ...string) {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.add(patterns)
+}
+
+// Unsubscribe removes patterns from the subscription, or all of them if none are given.
+// The subscription stays open and receives nothing until patterns are added again.
+func (s *ChannelSubscription) Unsubscribe(patterns ...string) {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if len(patterns) == 0 {
+		s.patterns = nil
+		return
+	}
+	s.patterns = slices.DeleteFunc(s.patterns, func(p string) bool { return slices.Contains(patterns, p) })
+}
+
+// Patterns returns the subscription's patterns in the order they were added.
+func (s *ChannelSubscription) Patterns() []string {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+	return slices.Clone(s.patterns)
+}
+
+// Dropped returns how many messages were discarded because the subscription's buffer was full.
+func (s *ChannelSubscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes C. It is safe to call Close more than once.
+func (s *ChannelSubscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// add appends the patterns the subscription does not have yet. The caller must hold broker.mu.
+func (s *ChannelSubscription) add(patterns []string) {
+	for _, p := range patterns {
+		if !slices.Contains(s.patterns, p) {
+			s.patterns = append(s.patterns, p)
+		}
+	}
+}
+
+// close closes every subscription and makes new ones start closed.
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		close(sub.ch)
+	}
+	b.subs = nil
+	b.closed = true
+}
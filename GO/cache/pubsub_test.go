@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+// received drains the messages currently buffered in the subscription.
+func received(sub *ChannelSubscription) []Message {
+	var msgs []Message
+	for {
+		select {
+		case m := <-sub.C:
+			msgs = append(msgs, m)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	news := c.SubscribeChannels(10, "news.*", "news.sport")
+	all := c.SubscribeChannels(10, "*")
+	exact := c.SubscribeChannels(10, "orders")
+
+	testCases := []struct {
+		channel string
+		want    int
+	}{
+		{"news.sport", 2},
+		{"orders", 2},
+		{"news", 1},
+	}
+	for _, tc := range testCases {
+		if got := c.Publish(tc.channel, tc.channel+"!"); got != tc.want {
+			t.Errorf("Publish(%q) = %d, want %d", tc.channel, got, tc.want)
+		}
+	}
+
+	wantNews := []Message{{Channel: "news.sport", Pattern: "news.*", Payload: "news.sport!"}}
+	if got := received(news); !reflect.DeepEqual(got, wantNews) {
+		t.Errorf("news received %v, want %v", got, wantNews)
+	}
+	if got := received(all); len(got) != 3 {
+		t.Errorf("* received %d messages, want 3", len(got))
+	}
+	wantOrders := []Message{{Channel: "orders", Pattern: "orders", Payload: "orders!"}}
+	if got := received(exact); !reflect.DeepEqual(got, wantOrders) {
+		t.Errorf("orders received %v, want %v", got, wantOrders)
+	}
+}
+
+func TestChannelSubscriptionChanges(t *testing.T) {
+	c := New()
+	defer c.Close()
+	sub := c.SubscribeChannels(1, "a")
+
+	sub.Subscribe("b", "a")
+	if got := sub.Patterns(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Patterns() = %v, want [a b]", got)
+	}
+	sub.Unsubscribe("a")
+	if got := c.Publish("a", 1); got != 0 {
+		t.Errorf("Publish() to an unsubscribed channel = %d, want 0", got)
+	}
+
+	c.Publish("b", 1)
+	c.Publish("b", 2)
+	if got := sub.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+	if m := <-sub.C; m.Payload != 1 {
+		t.Errorf("received payload %v, want 1", m.Payload)
+	}
+
+	sub.Unsubscribe()
+	if got := c.Publish("b", 3); got != 0 {
+		t.Errorf("Publish() after Unsubscribe() = %d, want 0", got)
+	}
+
+	sub.Close()
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Errorf("C is still open after Close")
+	}
+
+	other := c.SubscribeChannels(1, "*")
+	c.Close()
+	if _, ok := <-other.C; ok {
+		t.Errorf("C is still open after closing the cache")
+	}
+	if _, ok := <-c.SubscribeChannels(1, "*").C; ok {
+		t.Errorf("subscription made after closing the cache is open")
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"encoding/gob"
+	"math"
+	"time"
+)
+
+func init() {
+	gob.Register(bucketState{})
+	gob.Register(windowState{})
+}
+
+// TokenBucket limits how often each key may act: a key's bucket holds up to burst tokens,
+// refills at rate tokens per second, and every allowed event takes tokens from it.
+// The buckets are stored in the cache under the limiter's name followed by ':' and the key,
+// and expire once they have refilled, so idle keys cost nothing. A TokenBucket is safe for
+// concurrent use.
+type TokenBucket struct {
+	cache *Cache
+	name  string
+	rate  float64
+	burst int
+}
+
+// bucketState is the stored state of a token bucket.
+type bucketState struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// NewTokenBucket returns a token bucket limiter storing its buckets in c. rate must be positive.
+func NewTokenBucket(c *Cache, name string, rate float64, burst int) *TokenBucket {
+	return &TokenBucket{cache: c, name: name, rate: rate, burst: burst}
+}
+
+// Allow reports whether an event may happen now for the key, and if so takes a token.
+func (b *TokenBucket) Allow(key string) bool {
+	return b.AllowN(key, 1)
+}
+
+// AllowN reports whether n events may happen now for the key, and if so takes n tokens.
+func (b *TokenBucket) AllowN(key string, n int) bool {
+	return limit(b.cache, b.name+":"+key, func(s bucketState, found bool, now time.Time) (bucketState, time.Time, bool) {
+		tokens := float64(b.burst)
+		if found {
+			tokens = math.Min(tokens, s.Tokens+max(now.Sub(s.Updated), 0).Seconds()*b.rate)
+		}
+		allowed := tokens >= float64(n)
+		if allowed {
+			tokens -= float64(n)
+		}
+		full := time.Duration((float64(b.burst) - tokens) / b.rate * float64(time.Second))
+		return bucketState{Tokens: tokens, Updated: now}, now.Add(full), allowed
+	})
+}
+
+// Reset refills the key's bucket.
+func (b *TokenBucket) Reset(key string) {
+	b.cache.Delete(b.name + ":" + key)
+}
+
+// SlidingWindow limits each key to limit events within any window of the given length.
+// It counts events in fixed windows and weighs the previous window's count by how much of
+// it still overlaps the sliding window, which smooths out bursts at window boundaries
+// without storing every event. Counters are stored in the cache like the buckets of a
+// TokenBucket and expire after two windows. A SlidingWindow is safe for concurrent use.
+type SlidingWindow struct {
+	cache  *Cache
+	name   string
+	limit  int
+	window time.Duration
+}
+
+// windowState is the stored state of a sliding window: the start of the current fixed
+// window and the number of events in it and in the one before.
+type windowState struct {
+	Start    time.Time
+	Previous int
+	Current  int
+}
+
+// NewSlidingWindow returns a sliding window limiter storing its counters in c.
+// window must be positive.
+func NewSlidingWindow(c *Cache, name string, limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{cache: c, name: name, limit: limit, window: window}
+}
+
+// Allow reports whether an event may happen now for the key, and if so counts it.
+func (w *SlidingWindow) Allow(key string) bool {
+	return w.AllowN(key, 1)
+}
+
+// AllowN reports whether n events may happen now for the key, and if so counts them.
+func (w *SlidingWindow) AllowN(key string, n int) bool {
+	return limit(w.cache, w.name+":"+key, func(s windowState, found bool, now time.Time) (windowState, time.Time, bool) {
+		start := now.Truncate(w.window)
+		switch {
+		case !found || s.Start.Before(start.Add(-w.window)):
+			// The previous window and the current one are empty.
+			s = windowState{Start: start}
+		case s.Start.Before(start):
+			s = windowState{Start: start, Previous: s.Current}
+		}
+
+		overlap := 1 - float64(now.Sub(start))/float64(w.window)
+		allowed := float64(s.Previous)*overlap+float64(s.Current+n) <= float64(w.limit)
+		if allowed {
+			s.Current += n
+		}
+		return s, start.Add(2 * w.window), allowed
+	})
+}
+
+// Reset forgets the key's events.
+func (w *SlidingWindow) Reset(key string) {
+	w.cache.Delete(w.name + ":" + key)
+}
+
+// limit atomically updates the limiter state of type T stored under the key. fn receives the
+// current state, whether there is one, and the time; it returns the new state, when it
+// expires, and the result. A key holding a value of another type starts from a fresh state.
+func limit[T any](c *Cache, key string, fn func(state T, found bool, now time.Time) (T, time.Time, bool)) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	var state T
+	e, found := c.find(key)
+	if found && !e.expired(now) {
+		state, found = e.value.(T)
+	} else {
+		found = false
+	}
+
+	next, expiresAt, ok := fn(state, found, now)
+	c.set(key, next, expiresAt)
+	return ok
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	c := New(WithClock(clock))
+	defer c.Close()
+	b := NewTokenBucket(c, "api", 2, 3) // 2 tokens per second, bursts of 3
+
+	steps := []struct {
+		advance time.Duration
+		n       int
+		want    bool
+	}{
+		{0, 3, true},
+		{0, 1, false},
+		{500 * time.Millisecond, 1, true},
+		{0, 1, false},
+		{time.Second, 2, true},
+		{time.Hour, 4, false}, // more than the burst is never allowed
+		{0, 3, true},
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		if got := b.AllowN("alice", step.n); got != step.want {
+			t.Errorf("step %d: AllowN(alice, %d) = %v, want %v", i, step.n, got, step.want)
+		}
+	}
+	if !b.Allow("bob") {
+		t.Errorf("Allow(bob) = false, want true: keys have separate buckets")
+	}
+
+	b.Reset("alice")
+	if !b.AllowN("alice", 3) {
+		t.Errorf("AllowN() after Reset() = false, want true")
+	}
+
+	// A bucket expires from the cache once it has refilled.
+	clock.Advance(2 * time.Second)
+	if n := c.DeleteExpired(); n != 2 {
+		t.Errorf("DeleteExpired() = %d, want the 2 refilled buckets", n)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := newFakeClock()
+	clock.Advance(-clock.Now().Sub(clock.Now().Truncate(time.Minute))) // start on a window boundary
+	c := New(WithClock(clock))
+	defer c.Close()
+	w := NewSlidingWindow(c, "login", 10, time.Minute)
+
+	if !w.AllowN("ip", 10) {
+		t.Fatalf("AllowN(10) in an empty window = false, want true")
+	}
+	if w.Allow("ip") {
+		t.Errorf("Allow() over the limit = true, want false")
+	}
+
+	// A quarter into the next window three quarters of the previous 10 events still count.
+	clock.Advance(time.Minute + 15*time.Second)
+	if !w.AllowN("ip", 2) {
+		t.Errorf("AllowN(2) with 7.5 weighted events = false, want true")
+	}
+	if w.Allow("ip") {
+		t.Errorf("Allow() with 9.5 weighted events = true, want false")
+	}
+
+	clock.Advance(45 * time.Second)
+	if !w.AllowN("ip", 8) {
+		t.Errorf("AllowN(8) with 2 events in the previous window = false, want true")
+	}
+
+	// After two idle windows the key starts afresh and its counter has expired.
+	clock.Advance(2 * time.Minute)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Errorf("DeleteExpired() = %d, want 1", n)
+	}
+	if !w.AllowN("ip", 10) {
+		t.Errorf("AllowN(10) after idle windows = false, want true")
+	}
+}
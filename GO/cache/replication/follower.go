@@ -0,0 +1,123 @@
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// Follower mirrors the cache of a leader into a local cache.
+type Follower[K comparable, V any] struct {
+	cache *cache.TypedCache[K, V]
+	addr  string
+	cfg   config
+	syncs atomic.Uint64
+}
+
+// NewFollower creates a follower replicating the leader at addr into c. Call Run to start it.
+// For a *cache.Cache pass its TypedCache field.
+func NewFollower[K comparable, V any](c *cache.TypedCache[K, V], addr string, opts ...Option) *Follower[K, V] {
+	return &Follower[K, V]{cache: c, addr: addr, cfg: newConfig(opts)}
+}
+
+// Run connects to the leader, performs a full sync and applies the change stream. When the
+// link fails it reports the error to the error handler, waits for the retry interval and
+// reconnects with a new full sync. Run returns ctx.Err() once ctx is done.
+func (f *Follower[K, V]) Run(ctx context.Context) error {
+	for {
+		err := f.follow(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		f.cfg.errorHandler(fmt.Errorf("following %s: %w", f.addr, err))
+
+		select {
+		case <-time.After(f.cfg.retryInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Syncs returns how many full syncs the follower has completed.
+func (f *Follower[K, V]) Syncs() uint64 {
+	return f.syncs.Load()
+}
+
+// follow runs one connection to the leader until it fails or ctx is done.
+func (f *Follower[K, V]) follow(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", f.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// The snapshot and the stream are consecutive gob streams. bufio.Reader is an io.ByteReader,
+	// so neither decoder reads ahead past its own messages.
+	r := bufio.NewReader(conn)
+	timeout := 3 * f.cfg.heartbeat
+
+	// A large snapshot may take a while to build and send, so it gets no deadline of its own;
+	// a dead leader is noticed by the stream's heartbeat timeout instead. It is received into
+	// a scratch cache so that the local cache is only emptied once the snapshot has arrived.
+	if err := f.fullSync(r); err != nil {
+		return fmt.Errorf("full sync: %w", err)
+	}
+	dec := gob.NewDecoder(r)
+	for {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		var m message[K, V]
+		if err := dec.Decode(&m); err != nil {
+			return err
+		}
+		f.apply(m)
+	}
+}
+
+// fullSync replaces the contents of the local cache with the snapshot read from r.
+func (f *Follower[K, V]) fullSync(r io.Reader) error {
+	scratch := cache.NewTyped[K, V]()
+	defer scratch.Close()
+	if err := scratch.Load(r); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := scratch.Save(&buf); err != nil {
+		return err
+	}
+	f.cache.Clear()
+	if err := f.cache.Load(&buf); err != nil {
+		return err
+	}
+	f.syncs.Add(1)
+	return nil
+}
+
+// apply applies one message of the change stream to the local cache.
+func (f *Follower[K, V]) apply(m message[K, V]) {
+	switch m.Op {
+	case opSet:
+		ttl := cache.NoExpiration
+		if !m.ExpiresAt.IsZero() {
+			if ttl = time.Until(m.ExpiresAt); ttl <= 0 {
+				f.cache.Delete(m.Key)
+				return
+			}
+		}
+		f.cache.SetWith(m.Key, m.Value, cache.WithTTL(ttl), cache.WithTags(m.Tags...))
+	case opDelete:
+		f.cache.Delete(m.Key)
+	}
+}
@@ -0,0 +1,111 @@
+package replication
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+	"github.com/sKrasiuk/PubRep/GO/cache/internal/tcpserver"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Close has been called.
+var ErrServerClosed = tcpserver.ErrClosed
+
+// Leader serves the contents and changes of a cache to followers.
+type Leader[K comparable, V any] struct {
+	cache *cache.TypedCache[K, V]
+	cfg   config
+	tcp   *tcpserver.Server
+}
+
+// NewLeader creates a leader replicating c. For a *cache.Cache pass its TypedCache field.
+// The caller remains responsible for closing c.
+func NewLeader[K comparable, V any](c *cache.TypedCache[K, V], opts ...Option) *Leader[K, V] {
+	l := &Leader[K, V]{cache: c, cfg: newConfig(opts)}
+	l.tcp = tcpserver.New(l.serveConn)
+	return l
+}
+
+// ListenAndServe listens on the TCP address addr and serves followers until Close is called.
+func (l *Leader[K, V]) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return l.Serve(ln)
+}
+
+// Serve serves followers connecting to ln until Close is called.
+// It always returns a non-nil error; after Close it returns ErrServerClosed.
+func (l *Leader[K, V]) Serve(ln net.Listener) error {
+	return l.tcp.Serve(ln)
+}
+
+// Close stops accepting followers and disconnects the connected ones.
+func (l *Leader[K, V]) Close() error {
+	return l.tcp.Close()
+}
+
+// Followers returns the number of connected followers.
+func (l *Leader[K, V]) Followers() int {
+	return l.tcp.Conns()
+}
+
+// serveConn sends a follower the snapshot and then the change stream. It subscribes before
+// taking the snapshot, so changes made meanwhile are sent again after it; applying them twice
+// is harmless. It returns, closing the connection, when a write fails, when the follower fell
+// so far behind that changes were dropped, or when the cache is closed.
+func (l *Leader[K, V]) serveConn(conn net.Conn) {
+	sub := l.cache.Subscribe(l.cfg.buffer)
+	defer sub.Close()
+
+	w := bufio.NewWriter(conn)
+	deadline := func() { conn.SetWriteDeadline(time.Now().Add(3 * l.cfg.heartbeat)) }
+
+	conn.SetWriteDeadline(time.Time{})
+	if err := l.cache.Save(w); err != nil {
+		l.cfg.errorHandler(fmt.Errorf("sending snapshot to %s: %w", conn.RemoteAddr(), err))
+		return
+	}
+	deadline()
+	if err := w.Flush(); err != nil {
+		l.cfg.errorHandler(fmt.Errorf("sending snapshot to %s: %w", conn.RemoteAddr(), err))
+		return
+	}
+
+	enc := gob.NewEncoder(w)
+	heartbeat := time.NewTicker(l.cfg.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			err = enc.Encode(newMessage(ev))
+			// Send what has queued up meanwhile in the same write.
+			for n := len(sub.C); err == nil && n > 0; n-- {
+				err = enc.Encode(newMessage(<-sub.C))
+			}
+			if sub.Dropped() > 0 {
+				l.cfg.errorHandler(fmt.Errorf("follower %s fell behind, disconnecting it to resync", conn.RemoteAddr()))
+				return
+			}
+		case <-heartbeat.C:
+			err = enc.Encode(message[K, V]{Op: opHeartbeat})
+		}
+		if err == nil {
+			deadline()
+			err = w.Flush()
+		}
+		if err != nil {
+			l.cfg.errorHandler(fmt.Errorf("streaming to %s: %w", conn.RemoteAddr(), err))
+			return
+		}
+		heartbeat.Reset(l.cfg.heartbeat)
+	}
+}
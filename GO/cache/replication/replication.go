@@ -0,0 +1,114 @@
+// Package replication keeps follower caches in sync with a leader cache over TCP.
+//
+// The leader streams every change of its cache to connected followers. A follower that
+// connects, or reconnects after losing its link, first receives a full snapshot of the
+// leader's cache, which replaces its own contents, and then applies the change stream.
+// Followers should be treated as read-only: local writes are overwritten by the next full
+// sync and are not sent anywhere.
+//
+// Values are encoded with encoding/gob, so values stored as interface{} must have their
+// concrete types registered with gob.Register in both processes.
+package replication
+
+import (
+	"log"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// Defaults for the options.
+const (
+	DefaultBuffer        = 4096
+	DefaultHeartbeat     = time.Second
+	DefaultRetryInterval = time.Second
+)
+
+// Option configures a Leader or a Follower.
+type Option func(*config)
+
+type config struct {
+	buffer        int
+	heartbeat     time.Duration
+	retryInterval time.Duration
+	errorHandler  func(error)
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		buffer:        DefaultBuffer,
+		heartbeat:     DefaultHeartbeat,
+		retryInterval: DefaultRetryInterval,
+		errorHandler:  func(err error) { log.Printf("replication: %v", err) },
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithBuffer sets how many changes the leader queues for each follower. A follower that falls
+// further behind is disconnected and catches up with a full sync when it reconnects.
+func WithBuffer(n int) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.buffer = n
+		}
+	}
+}
+
+// WithHeartbeat sets how often the leader sends a heartbeat on an idle link. A follower
+// that hears nothing for three intervals considers the link dead and reconnects.
+// Leader and followers must use the same interval.
+func WithHeartbeat(d time.Duration) Option {
+	return func(cfg *config) {
+		if d > 0 {
+			cfg.heartbeat = d
+		}
+	}
+}
+
+// WithRetryInterval sets how long a follower waits before reconnecting after a failure.
+func WithRetryInterval(d time.Duration) Option {
+	return func(cfg *config) {
+		if d > 0 {
+			cfg.retryInterval = d
+		}
+	}
+}
+
+// WithErrorHandler sets the function receiving connection errors, which are otherwise
+// written with the standard log package.
+func WithErrorHandler(handler func(error)) Option {
+	return func(cfg *config) {
+		if handler != nil {
+			cfg.errorHandler = handler
+		}
+	}
+}
+
+// op identifies a message of the change stream.
+type op uint8
+
+const (
+	opSet op = iota + 1
+	opDelete
+	opHeartbeat
+)
+
+// message is one element of the change stream that follows the snapshot.
+type message[K comparable, V any] struct {
+	Op        op
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+	Tags      []string
+}
+
+// newMessage converts a cache event to a stream message.
+func newMessage[K comparable, V any](ev cache.Event[K, V]) message[K, V] {
+	if ev.Reason == cache.ReasonSet {
+		return message[K, V]{Op: opSet, Key: ev.Key, Value: ev.Value, ExpiresAt: ev.ExpiresAt, Tags: ev.Tags}
+	}
+	return message[K, V]{Op: opDelete, Key: ev.Key}
+}
@@ -0,0 +1,243 @@
+package replication
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// fast shortens the timings so that tests notice dead links quickly.
+var fast = []Option{WithHeartbeat(50 * time.Millisecond), WithRetryInterval(10 * time.Millisecond), WithErrorHandler(func(error) {})}
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// startLeader serves c on addr and returns the leader and the address it listens on.
+func startLeader(t *testing.T, c *cache.TypedCache[string, string], addr string, opts ...Option) (*Leader[string, string], string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLeader(c, opts...)
+	go l.Serve(ln)
+	t.Cleanup(func() { l.Close() })
+	return l, ln.Addr().String()
+}
+
+func startFollower(t *testing.T, c *cache.TypedCache[string, string], addr string) *Follower[string, string] {
+	t.Helper()
+	f := NewFollower(c, addr, fast...)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return f
+}
+
+func has(c *cache.TypedCache[string, string], key, want string) func() bool {
+	return func() bool {
+		v, ok := c.Get(key)
+		return ok && v == want
+	}
+}
+
+func TestFullSyncAndStream(t *testing.T) {
+	lc := cache.NewTyped[string, string]()
+	defer lc.Close()
+	lc.Set("a", "1")
+	lc.SetWith("b", "2", cache.WithTTL(time.Hour), cache.WithTags("t"))
+
+	_, addr := startLeader(t, lc, "127.0.0.1:0", fast...)
+	fc := cache.NewTyped[string, string]()
+	defer fc.Close()
+	fc.Set("local", "x")
+	f := startFollower(t, fc, addr)
+
+	waitFor(t, "full sync", func() bool { return f.Syncs() == 1 })
+	if _, ok := fc.Get("local"); ok {
+		t.Errorf("follower kept a local key across the full sync")
+	}
+	if v, _ := fc.Get("a"); v != "1" {
+		t.Errorf("follower Get(a) = %q, want 1", v)
+	}
+	if ttl, _ := fc.TTL("b"); ttl <= 59*time.Minute {
+		t.Errorf("follower TTL(b) = %v, want about 1h", ttl)
+	}
+	if tags := fc.Tags("b"); len(tags) != 1 || tags[0] != "t" {
+		t.Errorf("follower Tags(b) = %v, want [t]", tags)
+	}
+
+	lc.SetWith("c", "3", cache.WithTags("u"))
+	lc.Delete("a")
+	lc.Expire("b", cache.NoExpiration)
+	waitFor(t, "streamed set", has(fc, "c", "3"))
+	waitFor(t, "streamed delete", func() bool { _, ok := fc.Get("a"); return !ok })
+	waitFor(t, "streamed expire", func() bool { ttl, _ := fc.TTL("b"); return ttl == cache.NoExpiration })
+	if tags := fc.Tags("c"); len(tags) != 1 || tags[0] != "u" {
+		t.Errorf("follower Tags(c) = %v, want [u]", tags)
+	}
+
+	// Heartbeats keep an idle link alive.
+	time.Sleep(300 * time.Millisecond)
+	if got := f.Syncs(); got != 1 {
+		t.Errorf("Syncs() after an idle period = %d, want 1", got)
+	}
+}
+
+func TestReconnectResyncs(t *testing.T) {
+	lc := cache.NewTyped[string, string]()
+	defer lc.Close()
+	lc.Set("a", "1")
+
+	l, addr := startLeader(t, lc, "127.0.0.1:0", fast...)
+	fc := cache.NewTyped[string, string]()
+	defer fc.Close()
+	f := startFollower(t, fc, addr)
+	waitFor(t, "first sync", has(fc, "a", "1"))
+
+	l.Close()
+	// These changes are made while the follower is disconnected.
+	lc.Delete("a")
+	lc.Set("b", "2")
+
+	startLeader(t, lc, addr, fast...)
+	waitFor(t, "resync", func() bool { return f.Syncs() == 2 })
+	if _, ok := fc.Get("a"); ok {
+		t.Errorf("follower kept a key deleted while it was disconnected")
+	}
+	if v, _ := fc.Get("b"); v != "2" {
+		t.Errorf("follower Get(b) = %q, want 2", v)
+	}
+}
+
+func TestSlowFollowerIsDisconnected(t *testing.T) {
+	lc := cache.NewTyped[string, string]()
+	defer lc.Close()
+	var fellBehind atomic.Bool
+	l, addr := startLeader(t, lc, "127.0.0.1:0", WithBuffer(1), WithErrorHandler(func(err error) {
+		if strings.Contains(err.Error(), "fell behind") {
+			fellBehind.Store(true)
+		}
+	}))
+
+	// A follower that never reads: the leader blocks on the full socket and its queue overflows.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitFor(t, "follower connected", func() bool { return l.Followers() == 1 })
+
+	value := strings.Repeat("x", 64<<10)
+	for i := 0; !fellBehind.Load(); i++ {
+		lc.Set(fmt.Sprint(i), value)
+		if i > 100_000 {
+			t.Fatalf("leader never dropped the slow follower")
+		}
+	}
+	waitFor(t, "disconnect", func() bool { return l.Followers() == 0 })
+}
+
+// TestLeaderProcess is the leader half of TestReplicationAcrossProcesses. It only runs in the
+// child process started by that test, serving a cache holding the keys listed in the
+// environment until its standard input is closed.
+func TestLeaderProcess(t *testing.T) {
+	addr := os.Getenv("REPLICATION_LEADER_ADDR")
+	if addr == "" {
+		t.Skip("only runs as a child of TestReplicationAcrossProcesses")
+	}
+	c := cache.NewTyped[string, string]()
+	defer c.Close()
+	for _, key := range strings.Fields(os.Getenv("REPLICATION_LEADER_KEYS")) {
+		c.Set(key, "v")
+	}
+	l, _ := startLeader(t, c, addr, fast...)
+	fmt.Println("ready")
+
+	// Write one more key per line read from stdin until it is closed.
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		c.Set(in.Text(), "v")
+	}
+	l.Close()
+}
+
+func TestReplicationAcrossProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts child processes")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	startProcess := func(keys string) (*exec.Cmd, *bufio.Writer) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLeaderProcess$")
+		cmd.Env = append(os.Environ(), "REPLICATION_LEADER_ADDR="+addr, "REPLICATION_LEADER_KEYS="+keys)
+		stdin, _ := cmd.StdinPipe()
+		stdout, _ := cmd.StdoutPipe()
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		out := bufio.NewScanner(stdout)
+		if !out.Scan() || out.Text() != "ready" {
+			t.Fatalf("leader process did not start: %q", out.Text())
+		}
+		go func() {
+			for out.Scan() {
+			}
+		}()
+		return cmd, bufio.NewWriter(stdin)
+	}
+
+	leader, in := startProcess("a b")
+	fc := cache.NewTyped[string, string]()
+	defer fc.Close()
+	f := startFollower(t, fc, addr)
+	waitFor(t, "full sync from the first leader", func() bool { return fc.Len() == 2 })
+
+	fmt.Fprintln(in, "c")
+	in.Flush()
+	waitFor(t, "streamed key", has(fc, "c", "v"))
+
+	// Kill the leader and start another one with different contents on the same address.
+	leader.Process.Kill()
+	leader.Wait()
+	leader, _ = startProcess("x")
+	defer func() {
+		leader.Process.Kill()
+		leader.Wait()
+	}()
+
+	waitFor(t, "resync with the second leader", func() bool { return f.Syncs() == 2 && fc.Len() == 1 })
+	if _, ok := fc.Get("x"); !ok {
+		t.Errorf("follower misses the second leader's key after resync")
+	}
+}
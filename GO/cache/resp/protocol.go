@@ -0,0 +1,160 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Limits guarding the server against oversized requests. Memory is only allocated for data
+// as it arrives, so that a client cannot make the server reserve these sizes by announcing them.
+const (
+	maxBulkLen   = 512 << 20
+	maxArgs      = 1 << 20
+	maxInlineLen = 64 << 10 // also bounds the header lines of multibulk requests
+	preallocArgs = 1024
+)
+
+// errProtocol is returned when a client sends a malformed request.
+var errProtocol = errors.New("protocol error")
+
+// reader decodes client commands sent either as RESP arrays of bulk strings or as inline text.
+type reader struct {
+	br *bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{br: bufio.NewReader(r)}
+}
+
+// readCommand returns the next command and its arguments. Empty inline lines are skipped.
+func (r *reader) readCommand() ([]string, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != '*' {
+			if args := strings.Fields(line); len(args) > 0 {
+				return args, nil
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+		}
+		if n <= 0 {
+			continue
+		}
+
+		args := make([]string, 0, min(n, preallocArgs))
+		for range n {
+			arg, err := r.readBulk()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return args, nil
+	}
+}
+
+// readBulk reads a single $-prefixed bulk string.
+func (r *reader) readBulk() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return "", fmt.Errorf("%w: expected '$', got %q", errProtocol, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxBulkLen {
+		return "", fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+
+	var buf strings.Builder
+	if _, err := io.CopyN(&buf, r.br, int64(n)); err != nil {
+		return "", err
+	}
+	var crlf [2]byte
+	if _, err := io.ReadFull(r.br, crlf[:]); err != nil {
+		return "", err
+	}
+	if crlf != [2]byte{'\r', '\n'} {
+		return "", fmt.Errorf("%w: bulk string not terminated by CRLF", errProtocol)
+	}
+	return buf.String(), nil
+}
+
+// readLine reads a line of at most maxInlineLen bytes and strips its CRLF or LF terminator.
+func (r *reader) readLine() (string, error) {
+	var line []byte
+	for {
+		frag, err := r.br.ReadSlice('\n')
+		line = append(line, frag...)
+		if len(line) > maxInlineLen {
+			return "", fmt.Errorf("%w: too big inline request", errProtocol)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		s := strings.TrimSuffix(string(line), "\n")
+		return strings.TrimSuffix(s, "\r"), nil
+	}
+}
+
+// writer encodes RESP2 replies. Replies are buffered until flush is called.
+type writer struct {
+	bw *bufio.Writer
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{bw: bufio.NewWriter(w)}
+}
+
+func (w *writer) simple(s string) {
+	w.bw.WriteString("+" + s + "\r\n")
+}
+
+func (w *writer) error(msg string) {
+	w.bw.WriteString("-" + msg + "\r\n")
+}
+
+func (w *writer) integer(n int64) {
+	w.bw.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w *writer) bulk(s string) {
+	w.bw.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (w *writer) null() {
+	w.bw.WriteString("$-1\r\n")
+}
+
+func (w *writer) arrayHeader(n int) {
+	w.bw.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+func (w *writer) bulkArray(items []string) {
+	w.arrayHeader(len(items))
+	for _, item := range items {
+		w.bulk(item)
+	}
+}
+
+func (w *writer) flush() error {
+	return w.bw.Flush()
+}
@@ -0,0 +1,171 @@
+package resp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+	"github.com/sKrasiuk/PubRep/GO/cache/internal/glob"
+)
+
+// messageBuffer is how many published messages are queued for a subscribed connection
+// before further ones are dropped.
+const messageBuffer = 1024
+
+// session is the state of a client connection. Command replies and the messages pushed to
+// a subscribed connection share its writer.
+type session struct {
+	mu sync.Mutex // guards w
+	w  *writer
+
+	// SUBSCRIBE and PSUBSCRIBE subscriptions, created on first use, and their channels and
+	// patterns. Channels are subscribed to as quoted patterns so that they match literally.
+	channels, patterns         *cache.ChannelSubscription
+	channelNames, patternNames []string
+	pushers                    sync.WaitGroup
+}
+
+// subscribed reports whether the connection is in the subscribed state, where only the
+// pub/sub commands, PING and QUIT are allowed.
+func (sess *session) subscribed() bool {
+	return len(sess.channelNames)+len(sess.patternNames) > 0
+}
+
+// close ends the connection's subscriptions and waits for their messages to be pushed.
+func (sess *session) close() {
+	for _, sub := range []*cache.ChannelSubscription{sess.channels, sess.patterns} {
+		if sub != nil {
+			sub.Close()
+		}
+	}
+	sess.pushers.Wait()
+}
+
+// push writes the messages received by sub to the connection until sub is closed.
+func (sess *session) push(sub *cache.ChannelSubscription, pattern bool) {
+	defer sess.pushers.Done()
+	for m := range sub.C {
+		sess.mu.Lock()
+		writeMessage(sess.w, m, pattern)
+		// Send what has queued up meanwhile in the same write.
+		for n := len(sub.C); n > 0; n-- {
+			writeMessage(sess.w, <-sub.C, pattern)
+		}
+		sess.w.flush() // a failed write also ends the read loop, which closes sub
+		sess.mu.Unlock()
+	}
+}
+
+func writeMessage(w *writer, m cache.Message, pattern bool) {
+	payload, ok := formatValue(m.Payload)
+	if !ok {
+		payload = fmt.Sprint(m.Payload)
+	}
+	if pattern {
+		w.arrayHeader(4)
+		w.bulk("pmessage")
+		w.bulk(m.Pattern)
+	} else {
+		w.arrayHeader(3)
+		w.bulk("message")
+	}
+	w.bulk(m.Channel)
+	w.bulk(payload)
+}
+
+// sessionCommand is a command that changes the connection's state.
+type sessionCommand struct {
+	arity   int
+	handler func(s *Server, sess *session, args []string)
+}
+
+var sessionCommands = map[string]sessionCommand{
+	"SUBSCRIBE":    {-2, func(s *Server, sess *session, args []string) { s.subscribe(sess, args[1:], false) }},
+	"PSUBSCRIBE":   {-2, func(s *Server, sess *session, args []string) { s.subscribe(sess, args[1:], true) }},
+	"UNSUBSCRIBE":  {-1, func(s *Server, sess *session, args []string) { s.unsubscribe(sess, args[1:], false) }},
+	"PUNSUBSCRIBE": {-1, func(s *Server, sess *session, args []string) { s.unsubscribe(sess, args[1:], true) }},
+}
+
+func (s *Server) subscribe(sess *session, names []string, pattern bool) {
+	sub, subscribed, kind := &sess.channels, &sess.channelNames, "subscribe"
+	if pattern {
+		sub, subscribed, kind = &sess.patterns, &sess.patternNames, "psubscribe"
+	}
+	if *sub == nil {
+		*sub = s.cache.SubscribeChannels(messageBuffer)
+		sess.pushers.Add(1)
+		go sess.push(*sub, pattern)
+	}
+
+	for _, name := range names {
+		if !slices.Contains(*subscribed, name) {
+			*subscribed = append(*subscribed, name)
+			(*sub).Subscribe(subscriptionPattern(name, pattern))
+		}
+		writeSubscriptionReply(sess, kind, name)
+	}
+}
+
+func (s *Server) unsubscribe(sess *session, names []string, pattern bool) {
+	sub, subscribed, kind := sess.channels, &sess.channelNames, "unsubscribe"
+	if pattern {
+		sub, subscribed, kind = sess.patterns, &sess.patternNames, "punsubscribe"
+	}
+	if len(names) == 0 {
+		names = slices.Clone(*subscribed)
+		if len(names) == 0 {
+			sess.w.arrayHeader(3)
+			sess.w.bulk(kind)
+			sess.w.null()
+			sess.w.integer(int64(len(sess.channelNames) + len(sess.patternNames)))
+			return
+		}
+	}
+
+	for _, name := range names {
+		if i := slices.Index(*subscribed, name); i >= 0 {
+			*subscribed = slices.Delete(*subscribed, i, i+1)
+			sub.Unsubscribe(subscriptionPattern(name, pattern))
+		}
+		writeSubscriptionReply(sess, kind, name)
+	}
+}
+
+// subscriptionPattern returns the pattern matching a SUBSCRIBE channel or PSUBSCRIBE pattern.
+func subscriptionPattern(name string, pattern bool) string {
+	if pattern {
+		return name
+	}
+	return glob.Quote(name)
+}
+
+// writeSubscriptionReply confirms a change of subscription with the number of channels and
+// patterns the connection is now subscribed to.
+func writeSubscriptionReply(sess *session, kind, name string) {
+	sess.w.arrayHeader(3)
+	sess.w.bulk(kind)
+	sess.w.bulk(name)
+	sess.w.integer(int64(len(sess.channelNames) + len(sess.patternNames)))
+}
+
+func (s *Server) publish(w *writer, args []string) {
+	w.integer(int64(s.cache.Publish(args[1], args[2])))
+}
+
+// subscribedPing answers PING in the subscribed state, where it replies with an array.
+func subscribedPing(w *writer, args []string) {
+	w.arrayHeader(2)
+	w.bulk("pong")
+	if len(args) > 1 {
+		w.bulk(args[1])
+	} else {
+		w.bulk("")
+	}
+}
+
+// notAllowedWhileSubscribed is the error for other commands in the subscribed state.
+func notAllowedWhileSubscribed(name string) string {
+	return fmt.Sprintf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(name))
+}
@@ -0,0 +1,102 @@
+package resp
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// dial opens another connection to the server tc is connected to.
+func (tc *testClient) dial() *testClient {
+	tc.t.Helper()
+	conn, err := net.Dial("tcp", tc.conn.RemoteAddr().String())
+	if err != nil {
+		tc.t.Fatalf("Dial() error = %v", err)
+	}
+	tc.t.Cleanup(func() { conn.Close() })
+	return &testClient{t: tc.t, conn: conn, br: bufio.NewReader(conn)}
+}
+
+// next reads a message pushed to a subscribed connection.
+func (tc *testClient) next() interface{} {
+	tc.t.Helper()
+	reply, err := tc.readReply()
+	if err != nil {
+		tc.t.Fatalf("reading pushed message: %v", err)
+	}
+	return reply
+}
+
+func TestPubSub(t *testing.T) {
+	c, sub := startServer(t)
+	pub := sub.dial()
+
+	steps := []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"SUBSCRIBE", "news", "a*b"}, []interface{}{"subscribe", "news", int64(1)}},
+		{nil, []interface{}{"subscribe", "a*b", int64(2)}},
+		{[]string{"PSUBSCRIBE", "n?ws"}, []interface{}{"psubscribe", "n?ws", int64(3)}},
+		{[]string{"GET", "news"}, "-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context"},
+		{[]string{"PING"}, []interface{}{"pong", ""}},
+	}
+	for _, step := range steps {
+		var got interface{}
+		if step.args != nil {
+			got = sub.do(step.args...)
+		} else {
+			got = sub.next()
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%v = %#v, want %#v", step.args, got, step.want)
+		}
+	}
+
+	if got := pub.do("PUBLISH", "news", "hello"); got != int64(2) {
+		t.Errorf("PUBLISH news = %#v, want 2", got)
+	}
+	// The channel and pattern subscriptions push independently, so accept either order.
+	got := []interface{}{sub.next(), sub.next()}
+	message := []interface{}{"message", "news", "hello"}
+	pmessage := []interface{}{"pmessage", "n?ws", "news", "hello"}
+	if !reflect.DeepEqual(got, []interface{}{message, pmessage}) && !reflect.DeepEqual(got, []interface{}{pmessage, message}) {
+		t.Errorf("pushed %#v, want %#v and %#v", got, message, pmessage)
+	}
+
+	// Channels match literally, so "a*b" does not receive "axb".
+	if got := pub.do("PUBLISH", "axb", "x"); got != int64(0) {
+		t.Errorf("PUBLISH axb = %#v, want 0", got)
+	}
+	c.Publish("a*b", int64(7))
+	if got, want := sub.next(), []interface{}{"message", "a*b", "7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("message published from Go = %#v, want %#v", got, want)
+	}
+
+	steps = []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"UNSUBSCRIBE"}, []interface{}{"unsubscribe", "news", int64(2)}},
+		{nil, []interface{}{"unsubscribe", "a*b", int64(1)}},
+		{[]string{"PUNSUBSCRIBE", "n?ws"}, []interface{}{"punsubscribe", "n?ws", int64(0)}},
+		{[]string{"UNSUBSCRIBE"}, []interface{}{"unsubscribe", nil, int64(0)}},
+		{[]string{"PING"}, "PONG"},
+		{[]string{"SET", "k", "v"}, "OK"},
+	}
+	for _, step := range steps {
+		var got interface{}
+		if step.args != nil {
+			got = sub.do(step.args...)
+		} else {
+			got = sub.next()
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%v = %#v, want %#v", step.args, got, step.want)
+		}
+	}
+	if got := pub.do("PUBLISH", "news", "late"); got != int64(0) {
+		t.Errorf("PUBLISH after unsubscribing = %#v, want 0", got)
+	}
+}
@@ -0,0 +1,314 @@
+// Package resp serves a cache.Cache over TCP using the Redis serialization protocol (RESP2),
+// so that stock Redis clients can read and write the cache and use its pub/sub channels.
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+	"github.com/sKrasiuk/PubRep/GO/cache/internal/glob"
+	"github.com/sKrasiuk/PubRep/GO/cache/internal/tcpserver"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Close has been called.
+var ErrServerClosed = tcpserver.ErrClosed
+
+// Server answers RESP2 requests against a cache. Values written through the server are
+// stored as int64 when they are canonical decimal integers, so that INCR works on them,
+// and as strings otherwise.
+type Server struct {
+	cache *cache.Cache
+	tcp   *tcpserver.Server
+}
+
+// NewServer creates a server backed by c. The caller remains responsible for closing c.
+func NewServer(c *cache.Cache) *Server {
+	s := &Server{cache: c}
+	s.tcp = tcpserver.New(s.serveConn)
+	return s
+}
+
+// ListenAndServe listens on the TCP address addr and serves connections until Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and handles each on its own goroutine until Close is called.
+// It always returns a non-nil error; after Close it returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	return s.tcp.Serve(l)
+}
+
+// Close stops all listeners, closes open connections and waits for their handlers to return.
+func (s *Server) Close() error {
+	return s.tcp.Close()
+}
+
+// serveConn reads commands from the connection and writes their replies until it is closed.
+func (s *Server) serveConn(conn net.Conn) {
+	r := newReader(conn)
+	sess := &session{w: newWriter(conn)}
+	defer sess.close()
+	for {
+		args, err := r.readCommand()
+
+		sess.mu.Lock()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				sess.w.error("ERR " + err.Error())
+				sess.w.flush()
+			}
+			sess.mu.Unlock()
+			return
+		}
+		quit := s.dispatch(sess, args)
+		err = sess.w.flush()
+		sess.mu.Unlock()
+		if err != nil || quit {
+			return
+		}
+	}
+}
+
+// command describes a supported command. A negative arity is a minimum argument count,
+// counting the command name, as in Redis.
+type command struct {
+	arity   int
+	handler func(s *Server, w *writer, args []string)
+}
+
+var commands = map[string]command{
+	"PING":    {-1, (*Server).ping},
+	"GET":     {2, (*Server).get},
+	"SET":     {-3, (*Server).set},
+	"DEL":     {-2, (*Server).del},
+	"EXISTS":  {-2, (*Server).exists},
+	"EXPIRE":  {3, (*Server).expire},
+	"TTL":     {2, (*Server).ttl},
+	"INCR":    {2, (*Server).incr},
+	"KEYS":    {2, (*Server).keys},
+	"PUBLISH": {3, (*Server).publish},
+	"COMMAND": {-1, (*Server).command},
+}
+
+// dispatch runs a single command and reports whether the connection should be closed.
+// The caller must hold sess.mu.
+func (s *Server) dispatch(sess *session, args []string) bool {
+	w := sess.w
+	name := strings.ToUpper(args[0])
+	if name == "QUIT" {
+		w.simple("OK")
+		return true
+	}
+
+	if cmd, ok := sessionCommands[name]; ok {
+		if checkArity(w, name, cmd.arity, args) {
+			cmd.handler(s, sess, args)
+		}
+		return false
+	}
+	if sess.subscribed() {
+		switch {
+		case name != "PING":
+			w.error(notAllowedWhileSubscribed(name))
+		case len(args) > 2:
+			w.error("ERR wrong number of arguments for 'ping' command")
+		default:
+			subscribedPing(w, args)
+		}
+		return false
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		w.error(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+	if checkArity(w, name, cmd.arity, args) {
+		cmd.handler(s, w, args)
+	}
+	return false
+}
+
+// checkArity reports whether args has the number of arguments the command expects, and
+// writes the error reply if it does not.
+func checkArity(w *writer, name string, arity int, args []string) bool {
+	if (arity > 0 && len(args) != arity) || (arity < 0 && len(args) < -arity) {
+		w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return false
+	}
+	return true
+}
+
+func (s *Server) ping(w *writer, args []string) {
+	switch len(args) {
+	case 1:
+		w.simple("PONG")
+	case 2:
+		w.bulk(args[1])
+	default:
+		w.error("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func (s *Server) get(w *writer, args []string) {
+	v, ok := s.cache.Get(args[1])
+	if !ok {
+		w.null()
+		return
+	}
+	str, ok := formatValue(v)
+	if !ok {
+		w.error("WRONGTYPE Operation against a key holding the wrong kind of value")
+		return
+	}
+	w.bulk(str)
+}
+
+func (s *Server) set(w *writer, args []string) {
+	ttl := cache.DefaultExpiration
+	for i := 3; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if (opt != "EX" && opt != "PX") || i+1 >= len(args) {
+			w.error("ERR syntax error")
+			return
+		}
+		n, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil || n <= 0 {
+			w.error("ERR invalid expire time in 'set' command")
+			return
+		}
+		unit := time.Second
+		if opt == "PX" {
+			unit = time.Millisecond
+		}
+		if n > math.MaxInt64/int64(unit) {
+			w.error("ERR invalid expire time in 'set' command")
+			return
+		}
+		ttl = time.Duration(n) * unit
+		i++
+	}
+
+	s.cache.SetWithTTL(args[1], parseValue(args[2]), ttl)
+	w.simple("OK")
+}
+
+func (s *Server) del(w *writer, args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		if s.cache.Delete(key) {
+			n++
+		}
+	}
+	w.integer(n)
+}
+
+func (s *Server) exists(w *writer, args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		if _, ok := s.cache.TTL(key); ok {
+			n++
+		}
+	}
+	w.integer(n)
+}
+
+func (s *Server) expire(w *writer, args []string) {
+	seconds, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || seconds > math.MaxInt64/int64(time.Second) {
+		w.error("ERR value is not an integer or out of range")
+		return
+	}
+
+	var ok bool
+	if seconds <= 0 {
+		ok = s.cache.Delete(args[1])
+	} else {
+		ok = s.cache.Expire(args[1], time.Duration(seconds)*time.Second)
+	}
+	if ok {
+		w.integer(1)
+	} else {
+		w.integer(0)
+	}
+}
+
+func (s *Server) ttl(w *writer, args []string) {
+	d, ok := s.cache.TTL(args[1])
+	switch {
+	case !ok:
+		w.integer(-2)
+	case d == cache.NoExpiration:
+		w.integer(-1)
+	default:
+		w.integer(int64((d + 500*time.Millisecond) / time.Second))
+	}
+}
+
+func (s *Server) incr(w *writer, args []string) {
+	n, err := s.cache.Incr(args[1], 1)
+	switch {
+	case errors.Is(err, cache.ErrOverflow):
+		w.error("ERR increment or decrement would overflow")
+	case err != nil:
+		w.error("ERR value is not an integer or out of range")
+	default:
+		w.integer(n)
+	}
+}
+
+func (s *Server) keys(w *writer, args []string) {
+	var matched []string
+	for _, key := range s.cache.Keys() {
+		if glob.Match(args[1], key) {
+			matched = append(matched, key)
+		}
+	}
+	sort.Strings(matched)
+	w.bulkArray(matched)
+}
+
+// command answers the COMMAND introspection request sent by some clients on connect.
+func (s *Server) command(w *writer, args []string) {
+	w.arrayHeader(0)
+}
+
+// parseValue converts a client-supplied value to the type stored in the cache.
+func parseValue(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+		return n
+	}
+	return s
+}
+
+// formatValue renders a cached value as a RESP bulk string. It reports false for values
+// that have no string form, such as structs stored directly by Go code.
+func formatValue(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case []byte:
+		return string(x), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(x), true
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(x), true
+	}
+	return "", false
+}
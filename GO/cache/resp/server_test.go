@@ -0,0 +1,189 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sKrasiuk/PubRep/GO/cache"
+)
+
+// testClient is a minimal RESP client used to drive the server in tests.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func startServer(t *testing.T) (*cache.Cache, *testClient) {
+	t.Helper()
+
+	c := cache.New()
+	s := NewServer(c)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go s.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		s.Close()
+		c.Close()
+	})
+	return c, &testClient{t: t, conn: conn, br: bufio.NewReader(conn)}
+}
+
+// do sends a command as a RESP array and returns the decoded reply.
+func (tc *testClient) do(args ...string) interface{} {
+	tc.t.Helper()
+
+	req := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		req += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	if _, err := tc.conn.Write([]byte(req)); err != nil {
+		tc.t.Fatalf("Write() error = %v", err)
+	}
+	reply, err := tc.readReply()
+	if err != nil {
+		tc.t.Fatalf("reading reply to %v: %v", args, err)
+	}
+	return reply
+}
+
+// readReply decodes one reply. Errors are returned as "-message" strings and nil bulk strings as nil.
+func (tc *testClient) readReply() (interface{}, error) {
+	line, err := tc.br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return line, nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(tc.br, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = tc.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+func TestCommands(t *testing.T) {
+	_, client := startServer(t)
+
+	testCases := []struct {
+		args []string
+		want interface{}
+	}{
+		{args: []string{"PING"}, want: "PONG"},
+		{args: []string{"PING", "hi"}, want: "hi"},
+		{args: []string{"GET", "missing"}, want: nil},
+		{args: []string{"SET", "name", "ann"}, want: "OK"},
+		{args: []string{"GET", "name"}, want: "ann"},
+		{args: []string{"TTL", "name"}, want: int64(-1)},
+		{args: []string{"EXPIRE", "name", "100"}, want: int64(1)},
+		{args: []string{"TTL", "name"}, want: int64(100)},
+		{args: []string{"SET", "session", "x", "EX", "60"}, want: "OK"},
+		{args: []string{"TTL", "session"}, want: int64(60)},
+		{args: []string{"SET", "short", "x", "PX", "1500"}, want: "OK"},
+		{args: []string{"TTL", "short"}, want: int64(1)},
+		{args: []string{"SET", "bad", "x", "EX"}, want: "-ERR syntax error"},
+		{args: []string{"TTL", "missing"}, want: int64(-2)},
+		{args: []string{"EXISTS", "name", "missing", "session"}, want: int64(2)},
+		{args: []string{"INCR", "counter"}, want: int64(1)},
+		{args: []string{"SET", "counter", "41"}, want: "OK"},
+		{args: []string{"INCR", "counter"}, want: int64(42)},
+		{args: []string{"GET", "counter"}, want: "42"},
+		{args: []string{"INCR", "name"}, want: "-ERR value is not an integer or out of range"},
+		{args: []string{"KEYS", "s*"}, want: []interface{}{"session", "short"}},
+		{args: []string{"DEL", "name", "missing", "session"}, want: int64(2)},
+		{args: []string{"EXPIRE", "short", "0"}, want: int64(1)},
+		{args: []string{"KEYS", "*"}, want: []interface{}{"counter"}},
+		{args: []string{"GET"}, want: "-ERR wrong number of arguments for 'get' command"},
+		{args: []string{"NOPE"}, want: "-ERR unknown command 'NOPE'"},
+	}
+
+	for _, tc := range testCases {
+		if got := client.do(tc.args...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v = %#v, want %#v", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestInlineCommandsAndSharedCache(t *testing.T) {
+	c, client := startServer(t)
+	c.Set("shared", []byte("from go"))
+
+	if _, err := client.conn.Write([]byte("GET shared\r\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := client.readReply()
+	if err != nil || got != "from go" {
+		t.Errorf("inline GET = %v, %v, want from go", got, err)
+	}
+
+	client.do("SET", "n", "7")
+	if v, ok := c.Get("n"); !ok || v != int64(7) {
+		t.Errorf("cache.Get(n) = %#v, %v, want int64(7), true", v, ok)
+	}
+}
+
+func TestOversizedRequests(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     string
+		wantErr error
+	}{
+		{"announced bulk", "*1\r\n$536870912\r\nshort", io.EOF},
+		{"announced args", "*1048576\r\n$1\r\na\r\n", io.EOF},
+		{"inline line", strings.Repeat("a", maxInlineLen+1) + "\r\n", errProtocol},
+		{"header line", "*1\r\n$" + strings.Repeat("1", maxInlineLen) + "\r\n", errProtocol},
+	}
+
+	for _, tc := range testCases {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := newReader(strings.NewReader(tc.req)).readCommand()
+		runtime.ReadMemStats(&after)
+
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: readCommand() error = %v, want %v", tc.name, err, tc.wantErr)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%s: readCommand() allocated %d bytes, want memory only for the data sent", tc.name, allocated)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"slices"
+	"strings"
+)
+
+// scanBatch is the number of entries Range and the scans copy under each read lock when they
+// walk the key index.
+const scanBatch = 256
+
+// pair is a key and value copied out of the cache for a scan.
+type pair[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Range calls fn for every live entry until fn returns false. fn runs without the cache lock
+// held, so it may read and modify the cache. With WithKeyIndex the entries are visited in key
+// order and copied in small batches, each under a short read lock; otherwise they are all
+// copied under one read lock first. An entry written during the scan may or may not be
+// visited, and a visited value may have changed since it was copied.
+func (c *TypedCache[K, V]) Range(fn func(key K, value V) bool) {
+	if c.index != nil {
+		c.walk(c.index.first, func(K) bool { return true }, fn)
+		return
+	}
+	for _, p := range c.collect(func(K) bool { return true }) {
+		if !fn(p.key, p.value) {
+			return
+		}
+	}
+}
+
+// ScanRange calls fn in key order for every live entry whose key is in [start, end), until fn
+// returns false. Like Range, it calls fn without the lock held. With WithKeyIndex it seeks
+// directly to start; otherwise it copies and sorts the matching entries first.
+// It panics unless K is a built-in string or numeric type.
+func (c *TypedCache[K, V]) ScanRange(start, end K, fn func(key K, value V) bool) {
+	compare := orderedCompare[K]()
+	if compare == nil {
+		panic("cache: ScanRange requires a built-in string or numeric key type")
+	}
+	inRange := func(key K) bool { return compare(key, end) < 0 }
+
+	if c.index != nil {
+		c.walk(func() *skipNode[K] { return c.index.seek(start) }, inRange, fn)
+		return
+	}
+	pairs := c.collect(func(key K) bool { return compare(key, start) >= 0 && inRange(key) })
+	slices.SortFunc(pairs, func(a, b pair[K, V]) int { return compare(a.key, b.key) })
+	for _, p := range pairs {
+		if !fn(p.key, p.value) {
+			return
+		}
+	}
+}
+
+// ScanPrefix calls fn in key order for every live entry whose key starts with prefix, until fn
+// returns false. Like Range, it calls fn without the lock held. With WithKeyIndex it seeks
+// directly to the prefix; otherwise it copies and sorts the matching entries first.
+func (c *Cache) ScanPrefix(prefix string, fn func(key string, value interface{}) bool) {
+	hasPrefix := func(key string) bool { return strings.HasPrefix(key, prefix) }
+
+	if c.index != nil {
+		c.walk(func() *skipNode[string] { return c.index.seek(prefix) }, hasPrefix, fn)
+		return
+	}
+	pairs := c.collect(hasPrefix)
+	slices.SortFunc(pairs, func(a, b pair[string, interface{}]) int { return strings.Compare(a.key, b.key) })
+	for _, p := range pairs {
+		if !fn(p.key, p.value) {
+			return
+		}
+	}
+}
+
+// collect copies the live entries whose key matches under a read lock.
+func (c *TypedCache[K, V]) collect(match func(key K) bool) []pair[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	var pairs []pair[K, V]
+	for key, e := range c.store {
+		if !e.expired(now) && match(key) {
+			pairs = append(pairs, pair[K, V]{key, c.detach(e.value)})
+		}
+	}
+	return pairs
+}
+
+// walk visits the key index from the node returned by start while inRange holds, calling fn
+// for each live entry. It copies up to scanBatch entries per read lock and resumes after the
+// last visited key, so writes between batches are not blocked for the whole scan.
+func (c *TypedCache[K, V]) walk(start func() *skipNode[K], inRange func(key K) bool, fn func(key K, value V) bool) {
+	batch := make([]pair[K, V], 0, scanBatch)
+	var last K
+	for resume := false; ; resume = true {
+		batch = batch[:0]
+
+		c.mu.RLock()
+		var n *skipNode[K]
+		if !resume {
+			n = start()
+		} else if n = c.index.seek(last); n != nil && n.key == last {
+			n = n.next[0]
+		}
+		now := c.clock.Now()
+		for ; n != nil && len(batch) < scanBatch; n = n.next[0] {
+			if !inRange(n.key) {
+				break
+			}
+			if e := c.store[n.key]; !e.expired(now) {
+				batch = append(batch, pair[K, V]{n.key, c.detach(e.value)})
+			}
+			last = n.key
+		}
+		done := n == nil || !inRange(n.key)
+		c.mu.RUnlock()
+
+		for _, p := range batch {
+			if !fn(p.key, p.value) {
+				return
+			}
+		}
+		if done {
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "map", opts: nil},
+		{name: "index", opts: []Option{WithKeyIndex()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock()
+			c := New(append(tc.opts, WithClock(clock))...)
+			defer c.Close()
+
+			const n = 3*scanBatch + 10
+			for i := range n {
+				c.Set(fmt.Sprintf("k%04d", i), i)
+			}
+			c.SetWithTTL("expired", 0, time.Second)
+			clock.Advance(time.Second)
+
+			seen := 0
+			c.Range(func(key string, value interface{}) bool {
+				if key == "expired" {
+					t.Errorf("Range visited an expired entry")
+				}
+				// The lock is not held, so fn may write to the cache.
+				c.Delete(key)
+				seen++
+				return true
+			})
+			if seen != n {
+				t.Errorf("Range visited %d entries, want %d", seen, n)
+			}
+			if got := c.Len(); got != 1 {
+				t.Errorf("Len() after deleting from Range = %d, want only the expired entry left", got)
+			}
+
+			c.Set("a", 1)
+			c.Set("b", 2)
+			calls := 0
+			c.Range(func(key string, value interface{}) bool {
+				calls++
+				return false
+			})
+			if calls != 1 {
+				t.Errorf("Range called fn %d times after it returned false, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestScanPrefixAndRange(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "map", opts: nil},
+		{name: "index", opts: []Option{WithKeyIndex()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New(tc.opts...)
+			defer c.Close()
+			for _, key := range []string{"user:3", "post:1", "user:1", "user:20", "user:2", "users"} {
+				c.Set(key, key)
+			}
+
+			var keys []string
+			c.ScanPrefix("user:", func(key string, value interface{}) bool {
+				keys = append(keys, key)
+				return true
+			})
+			if want := []string{"user:1", "user:2", "user:20", "user:3"}; !slices.Equal(keys, want) {
+				t.Errorf("ScanPrefix(user:) = %v, want %v", keys, want)
+			}
+
+			keys = nil
+			c.ScanRange("post:", "user:3", func(key string, value interface{}) bool {
+				keys = append(keys, key)
+				return len(keys) < 4
+			})
+			if want := []string{"post:1", "user:1", "user:2", "user:20"}; !slices.Equal(keys, want) {
+				t.Errorf("ScanRange(post:, user:3) = %v, want %v", keys, want)
+			}
+		})
+	}
+}
+
+func TestScanRangeIntegerKeys(t *testing.T) {
+	c := NewTyped[int, string](WithKeyIndex())
+	defer c.Close()
+	for i := range 2000 {
+		c.Set(i, "v")
+	}
+
+	var keys []int
+	c.ScanRange(995, 1005, func(key int, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if want := []int{995, 996, 997, 998, 999, 1000, 1001, 1002, 1003, 1004}; !slices.Equal(keys, want) {
+		t.Errorf("ScanRange(995, 1005) = %v, want %v", keys, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ScanRange on an unordered key type did not panic")
+		}
+	}()
+	NewTyped[struct{ a int }, int]().ScanRange(struct{ a int }{}, struct{ a int }{1}, func(struct{ a int }, int) bool { return true })
+}
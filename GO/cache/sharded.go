@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// DefaultShards is the number of segments used by NewSharded when shards is not positive.
+const DefaultShards = 16
+
+// Sharded is a cache that spreads keys over independently locked TypedCache segments,
+// so writers to different segments do not contend on a single mutex.
+// It offers the same API as TypedCache.
+type Sharded[K comparable, V any] struct {
+	shards    []*TypedCache[K, V]
+	mask      uint64
+	seed      maphash.Seed
+	janitor   *janitor
+	closeOnce sync.Once
+}
+
+// NewSharded creates a cache with the given number of segments, rounded up to a power of two.
+// The options apply to every segment, except that WithMaxEntries and WithMaxCost bound the
+// whole cache: each segment holds an equal share of the limits and evicts independently.
+// If a cleanup interval is configured, a single janitor sweeps all segments.
+// WithSnapshot is not supported and makes NewSharded panic.
+func NewSharded[K comparable, V any](shards int, opts ...Option) *Sharded[K, V] {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	o := newOptions(opts)
+	if o.snapshotPath != "" {
+		panic("cache: WithSnapshot is not supported by NewSharded")
+	}
+	if o.maxEntries > 0 {
+		o.maxEntries = (o.maxEntries + n - 1) / n
+	}
+	if o.maxCost > 0 {
+		o.maxCost = (o.maxCost + int64(n) - 1) / int64(n)
+	}
+
+	s := &Sharded[K, V]{
+		shards: make([]*TypedCache[K, V], n),
+		mask:   uint64(n - 1),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range s.shards {
+		s.shards[i] = newTyped[K, V](o)
+	}
+
+	if o.cleanupInterval > 0 {
+		s.janitor = newJanitor(o.cleanupInterval)
+		go s.janitor.run(func() { s.DeleteExpired() })
+	}
+	return s
+}
+
+// Set stores a value under the key using the cache's default TTL.
+func (s *Sharded[K, V]) Set(key K, value V) {
+	s.shard(key).Set(key, value)
+}
+
+// SetWithTTL stores a value under the key that expires after ttl.
+func (s *Sharded[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	s.shard(key).SetWithTTL(key, value, ttl)
+}
+
+// Get returns the value stored under the key. Expired entries are reported as misses.
+func (s *Sharded[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Delete removes the key from the cache and reports whether a live entry was removed.
+func (s *Sharded[K, V]) Delete(key K) bool {
+	return s.shard(key).Delete(key)
+}
+
+// DeleteExpired removes every expired entry from all segments and returns how many were removed.
+func (s *Sharded[K, V]) DeleteExpired() int {
+	removed := 0
+	for _, shard := range s.shards {
+		removed += shard.DeleteExpired()
+	}
+	return removed
+}
+
+// Len returns the number of stored entries across all segments.
+func (s *Sharded[K, V]) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Evictions returns how many entries have been evicted across all segments.
+func (s *Sharded[K, V]) Evictions() uint64 {
+	var n uint64
+	for _, shard := range s.shards {
+		n += shard.Evictions()
+	}
+	return n
+}
+
+// Stats returns the sum of the counters of all segments.
+func (s *Sharded[K, V]) Stats() Stats {
+	var st Stats
+	for _, shard := range s.shards {
+		st = st.add(shard.Stats())
+	}
+	return st
+}
+
+// Close stops the background janitor, if any. It is safe to call Close more than once.
+func (s *Sharded[K, V]) Close() {
+	s.closeOnce.Do(func() {
+		if s.janitor != nil {
+			s.janitor.stop()
+		}
+	})
+}
+
+// shard returns the segment responsible for the key.
+func (s *Sharded[K, V]) shard(key K) *TypedCache[K, V] {
+	return s.shards[maphash.Comparable(s.seed, key)&s.mask]
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSharded(t *testing.T) {
+	s := NewSharded[string, int](3, WithMaxEntries(8))
+	defer s.Close()
+
+	if got := len(s.shards); got != 4 {
+		t.Fatalf("len(shards) = %d, want 4", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), i)
+	}
+	if got := s.Len(); got > 8 {
+		t.Errorf("Len() = %d, want at most 8", got)
+	}
+	if got := uint64(100 - s.Len()); s.Evictions() != got {
+		t.Errorf("Evictions() = %d, want %d", s.Evictions(), got)
+	}
+
+	s.Set("k", 1)
+	if v, ok := s.Get("k"); !ok || v != 1 {
+		t.Errorf("Get(k) = %v, %v, want 1, true", v, ok)
+	}
+	s.Delete("k")
+	if _, ok := s.Get("k"); ok {
+		t.Errorf("Get(k) after Delete found a value")
+	}
+}
+
+// benchCache is the API shared by Cache and Sharded that the benchmarks exercise.
+type benchCache interface {
+	Set(key string, value interface{})
+	Get(key string) (interface{}, bool)
+}
+
+const benchKeys = 1 << 14
+
+// benchmarkMixed runs a parallel workload where every writePercent-th operation out of 100 is a Set.
+func benchmarkMixed(b *testing.B, c benchCache, writePercent int) {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		c.Set(keys[i], i)
+	}
+
+	var worker atomic.Uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(worker.Add(1)) * 7919
+		for pb.Next() {
+			key := keys[i&(benchKeys-1)]
+			if i%100 < writePercent {
+				c.Set(key, i)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMixedParallel(b *testing.B) {
+	for _, writes := range []int{10, 50} {
+		b.Run("Cache/writes="+strconv.Itoa(writes), func(b *testing.B) {
+			c := New()
+			defer c.Close()
+			benchmarkMixed(b, c, writes)
+		})
+		b.Run("Sharded/writes="+strconv.Itoa(writes), func(b *testing.B) {
+			s := NewSharded[string, interface{}](DefaultShards)
+			defer s.Close()
+			benchmarkMixed(b, s, writes)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	snapshotMagic   = "pubrep-cache"
+	snapshotVersion = 1
+)
+
+// ErrSnapshotFormat is returned by Load when the input is not a snapshot this package can read.
+var ErrSnapshotFormat = errors.New("cache: unsupported snapshot format")
+
+// snapshotHeader starts every snapshot and identifies its format version.
+type snapshotHeader struct {
+	Magic   string
+	Version int
+	Count   int
+}
+
+// snapshotRecord is a single serialised entry. A zero ExpiresAt means the entry never expires.
+type snapshotRecord[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+	Tags      []string
+	Cost      int64
+}
+
+// Save writes all live entries, with their expiration deadlines and tags, to w using encoding/gob.
+// Entries are written from least to most recently used so that Load restores their recency.
+// Values stored as interface{} must have their concrete types registered with gob.Register.
+func (c *TypedCache[K, V]) Save(w io.Writer) error {
+	records := c.snapshot()
+
+	enc := gob.NewEncoder(w)
+	header := snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion, Count: len(records)}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return fmt.Errorf("cache: encoding key %v: %w", records[i].Key, err)
+		}
+	}
+	return nil
+}
+
+// Load reads a snapshot written by Save and stores its entries, overwriting existing keys.
+// Entries that have expired since the snapshot was taken are skipped.
+func (c *TypedCache[K, V]) Load(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotFormat, err)
+	}
+	if header.Magic != snapshotMagic || header.Version != snapshotVersion {
+		return fmt.Errorf("%w: %q version %d", ErrSnapshotFormat, header.Magic, header.Version)
+	}
+	if header.Count < 0 {
+		return fmt.Errorf("%w: negative entry count %d", ErrSnapshotFormat, header.Count)
+	}
+
+	// The records are decoded one at a time rather than into a slice sized by the header,
+	// so a corrupt count cannot force a huge allocation. All of them are decoded before any
+	// is stored, so a truncated snapshot leaves the cache unchanged.
+	var records []snapshotRecord[K, V]
+	for range header.Count {
+		var rec snapshotRecord[K, V]
+		if err := dec.Decode(&rec); err != nil {
+			return err
+		}
+		records = append(records, rec)
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	now := c.clock.Now()
+	for _, rec := range records {
+		if !rec.ExpiresAt.IsZero() && !now.Before(rec.ExpiresAt) {
+			continue
+		}
+		c.put(rec.Key, rec.Value, rec.ExpiresAt, rec.Tags, rec.Cost)
+	}
+	return nil
+}
+
+// SaveFile atomically replaces the file at path with a snapshot of the cache.
+// The snapshot is written and synced to a temporary file in the same directory first.
+func (c *TypedCache[K, V]) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := c.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile restores a snapshot previously written with SaveFile or WithSnapshot.
+func (c *TypedCache[K, V]) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f)
+}
+
+// snapshot copies the live entries from least to most recently used.
+func (c *TypedCache[K, V]) snapshot() []snapshotRecord[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	records := make([]snapshotRecord[K, V], 0, len(c.store))
+	for e := c.lru.back(); e != nil; e = e.prev {
+		if e.expired(now) {
+			continue
+		}
+		records = append(records, snapshotRecord[K, V]{Key: e.key, Value: c.detach(e.value), ExpiresAt: e.expiresAt, Tags: e.tags, Cost: e.cost})
+	}
+	return records
+}
+
+// autoSnapshot writes the configured snapshot file and reports failures to the error handler.
+func (c *TypedCache[K, V]) autoSnapshot() {
+	if err := c.SaveFile(c.snapshotPath); err != nil {
+		c.errorHandler(fmt.Errorf("cache: snapshot %s: %w", c.snapshotPath, err))
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	clock := newFakeClock()
+	src := New(WithClock(clock))
+	defer src.Close()
+
+	src.Set("forever", "v")
+	src.SetWithTTL("short", 1, time.Second)
+	src.SetWithTTL("long", 2.5, time.Hour)
+	src.SetWith("costly", "v", WithCost(7))
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	clock.Advance(2 * time.Second)
+	dst := New(WithClock(clock))
+	defer dst.Close()
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if v, ok := dst.Get("forever"); !ok || v != "v" {
+		t.Errorf("Get(forever) = %v, %v, want v, true", v, ok)
+	}
+	if _, ok := dst.Get("short"); ok {
+		t.Errorf("Get(short) hit, want entry expired before load to be skipped")
+	}
+	if v, ok := dst.Get("long"); !ok || v != 2.5 {
+		t.Errorf("Get(long) = %v, %v, want 2.5, true", v, ok)
+	}
+	if got := dst.Stats().Cost; got != 9 {
+		t.Errorf("Stats().Cost = %d, want 9 with explicit costs restored", got)
+	}
+
+	clock.Advance(time.Hour)
+	if _, ok := dst.Get("long"); ok {
+		t.Errorf("Get(long) hit after its TTL, want restored deadline to apply")
+	}
+}
+
+func TestLoadRejectsUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion + 1})
+
+	c := New()
+	defer c.Close()
+	if err := c.Load(&buf); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("Load() error = %v, want ErrSnapshotFormat", err)
+	}
+}
+
+func TestLoadRejectsBadCounts(t *testing.T) {
+	testCases := []struct {
+		name    string
+		count   int
+		wantErr error
+	}{
+		{name: "negative", count: -1, wantErr: ErrSnapshotFormat},
+		{name: "larger than the records", count: 1 << 60, wantErr: io.EOF},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gob.NewEncoder(&buf).Encode(snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion, Count: tc.count})
+
+			c := New()
+			defer c.Close()
+			if err := c.Load(&buf); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Load() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestShardedRejectsSnapshot(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewSharded() with WithSnapshot did not panic")
+		}
+	}()
+	NewSharded[string, int](4, WithSnapshot(filepath.Join(t.TempDir(), "snap"), time.Minute))
+}
+
+func TestAutoSnapshotOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	c := NewTyped[string, int](WithSnapshot(path, time.Hour))
+	c.Set("a", 1)
+	c.Close()
+
+	restored := NewTyped[string, int]()
+	defer restored.Close()
+	if err := restored.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if v, ok := restored.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+
+	matches, _ := filepath.Glob(path + ".tmp-*")
+	if len(matches) != 0 {
+		t.Errorf("temporary snapshot files left behind: %v", matches)
+	}
+}
@@ -0,0 +1,94 @@
+package cache
+
+import "sync/atomic"
+
+// Stats is a point-in-time snapshot of a cache's counters. The counters only grow, except
+// Size and Cost, which are the current number and total cost of the stored entries,
+// including expired ones not yet removed.
+type Stats struct {
+	Hits        uint64 // lookups that found a live entry
+	Misses      uint64 // lookups that found nothing or an expired entry
+	Sets        uint64 // values written
+	Deletes     uint64 // entries removed explicitly, for example by Delete or Clear
+	Evictions   uint64 // entries removed to respect the capacity
+	Expirations uint64 // expired entries removed or overwritten
+	Rejections  uint64 // new values not stored by the admission policy
+	Size        int
+	Cost        int64
+}
+
+// HitRatio returns the fraction of lookups that were hits, or 0 before the first lookup.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// add returns the sum of both snapshots, for aggregating the segments of a Sharded cache.
+func (s Stats) add(o Stats) Stats {
+	return Stats{
+		Hits:        s.Hits + o.Hits,
+		Misses:      s.Misses + o.Misses,
+		Sets:        s.Sets + o.Sets,
+		Deletes:     s.Deletes + o.Deletes,
+		Evictions:   s.Evictions + o.Evictions,
+		Expirations: s.Expirations + o.Expirations,
+		Rejections:  s.Rejections + o.Rejections,
+		Size:        s.Size + o.Size,
+		Cost:        s.Cost + o.Cost,
+	}
+}
+
+// counters are the atomic counters behind Stats. They can be read without taking the cache lock.
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	rejections  atomic.Uint64
+	size        atomic.Int64
+	cost        atomic.Int64
+}
+
+// Stats returns a snapshot of the cache's counters. Reading them does not block the cache,
+// so the individual values may be a few operations apart under concurrent use.
+func (c *TypedCache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:        c.stats.hits.Load(),
+		Misses:      c.stats.misses.Load(),
+		Sets:        c.stats.sets.Load(),
+		Deletes:     c.stats.deletes.Load(),
+		Evictions:   c.stats.evictions.Load(),
+		Expirations: c.stats.expirations.Load(),
+		Rejections:  c.stats.rejections.Load(),
+		Size:        int(c.stats.size.Load()),
+		Cost:        c.stats.cost.Load(),
+	}
+}
+
+// countLookup records a hit or a miss.
+func (c *TypedCache[K, V]) countLookup(hit bool) {
+	if hit {
+		c.stats.hits.Add(1)
+	} else {
+		c.stats.misses.Add(1)
+	}
+}
+
+// countRemoval records an entry or value leaving the cache for the given reason.
+func (c *TypedCache[K, V]) countRemoval(reason Reason) {
+	switch reason {
+	case ReasonDeleted:
+		c.stats.deletes.Add(1)
+	case ReasonEvicted:
+		c.stats.evictions.Add(1)
+	case ReasonExpired:
+		c.stats.expirations.Add(1)
+	case ReasonRejected:
+		c.stats.rejections.Add(1)
+	}
+}
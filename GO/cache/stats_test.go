@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	clock := newFakeClock()
+	c := New(WithClock(clock), WithMaxEntries(2))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("a", 2)
+	c.Get("a")
+	c.Get("missing")
+	c.SetWithTTL("b", 1, time.Second)
+	c.Set("c", 1)
+	c.Delete("c")
+	c.SetWithTTL("d", 1, time.Second)
+	clock.Advance(time.Second)
+	c.Get("d")
+	c.DeleteExpired()
+
+	want := Stats{Hits: 1, Misses: 2, Sets: 5, Deletes: 1, Evictions: 1, Expirations: 2, Size: 0}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got := c.Stats().HitRatio(); got != 1.0/3 {
+		t.Errorf("HitRatio() = %v, want %v", got, 1.0/3)
+	}
+	if got := c.Evictions(); got != 1 {
+		t.Errorf("Evictions() = %d, want 1", got)
+	}
+
+	c.Set("e", 1)
+	c.Set("f", 1)
+	c.Clear()
+	if got := c.Stats(); got.Deletes != 3 || got.Size != 0 {
+		t.Errorf("Stats() after Clear = %+v, want 3 deletes and size 0", got)
+	}
+}
+
+func TestShardedStats(t *testing.T) {
+	s := NewSharded[int, int](4)
+	defer s.Close()
+
+	for i := range 10 {
+		s.Set(i, i)
+		s.Get(i)
+	}
+	s.Get(100)
+
+	if got := s.Stats(); got.Hits != 10 || got.Misses != 1 || got.Sets != 10 || got.Size != 10 {
+		t.Errorf("Stats() = %+v, want 10 hits, 1 miss, 10 sets and size 10", got)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	c := New()
+	defer c.Close()
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("b")
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(c).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want Prometheus text format", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE cache_hits_total counter\ncache_hits_total 1\n",
+		"cache_misses_total 1\n",
+		"cache_sets_total 1\n",
+		"# TYPE cache_entries gauge\ncache_entries 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Store is a backing data source, such as a database, that the cache reads through and
+// writes to. Load must return ErrNotFound for keys that do not exist.
+type Store[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (V, error)
+	Save(ctx context.Context, key K, value V) error
+	Delete(ctx context.Context, key K) error
+}
+
+// WithStore backs the cache with s. Fetch reads through to s on a miss, and Write and Remove
+// write through to it: the store is updated first and the cache only if that succeeds.
+// Combine it with WithWriteBehind to update the store asynchronously instead.
+// Set, Delete and the other methods only change the cache.
+// The key and value types must match the cache's, otherwise NewTyped panics.
+func WithStore[K comparable, V any](s Store[K, V]) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithWriteBehind makes Write and Remove update the cache immediately and queue the change
+// for the store, which receives the queued changes in batches every interval. Only the latest
+// change of a key is kept, so a key written many times between flushes is saved once.
+// A change the store fails to apply is retried on the following flushes, at most retries
+// times, and then dropped and reported to the error handler. Call Flush, or Close, on
+// shutdown to apply the pending changes.
+func WithWriteBehind(interval time.Duration, retries int) Option {
+	return func(o *options) {
+		o.writeBehindInterval = interval
+		o.writeBehindRetries = retries
+	}
+}
+
+// pendingWrite is a change of a key waiting to be applied to the store by a write-behind flush.
+type pendingWrite[V any] struct {
+	value    V
+	deleted  bool
+	attempts int
+}
+
+// writeBehind is the queue of changes not yet applied to the store.
+type writeBehind[K comparable, V any] struct {
+	mu      sync.Mutex
+	pending map[K]*pendingWrite[V]
+
+	flushMu sync.Mutex // serializes flushes so that changes of a key reach the store in order
+	retries int
+	flusher *janitor
+}
+
+// keyLocks serializes Write and Remove calls per key, so that the cache and the store end
+// up holding the same value when one key is written concurrently. The zero value is ready to use.
+type keyLocks[K comparable] struct {
+	mu    sync.Mutex
+	locks map[K]*keyLock
+}
+
+type keyLock struct {
+	mu    sync.Mutex
+	users int // holders and waiters; the lock is dropped from the map when it reaches zero
+}
+
+// lock locks the key and returns the function unlocking it.
+func (l *keyLocks[K]) lock(key K) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[K]*keyLock)
+	}
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.users++
+	l.mu.Unlock()
+
+	kl.mu.Lock()
+	return func() {
+		kl.mu.Unlock()
+		l.mu.Lock()
+		if kl.users--; kl.users == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Fetch returns the value stored under the key, loading it from the store configured with
+// WithStore on a miss. Concurrent misses of a key share one load, and failures are remembered
+// as configured by WithNegativeTTL and WithErrorTTL, like GetOrLoad. Changes still queued by
+// write-behind take precedence over the store. Without a store a miss reports ErrNotFound.
+func (c *TypedCache[K, V]) Fetch(ctx context.Context, key K) (V, error) {
+	return c.GetOrLoad(ctx, key, c.loadFromStore)
+}
+
+// Write stores the value under the key in both the cache, using the default TTL, and the store.
+// With write-through, a failure to save it is returned and leaves the cache unchanged. With
+// write-behind, the save is queued and Write only fails if ctx is done. Without a store Write
+// is the same as Set.
+func (c *TypedCache[K, V]) Write(ctx context.Context, key K, value V) error {
+	defer c.writeLocks.lock(key)()
+	switch {
+	case c.behind != nil:
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.behind.queue(key, &pendingWrite[V]{value: value})
+	case c.backing != nil:
+		if err := c.backing.Save(ctx, key, value); err != nil {
+			return fmt.Errorf("cache: save %v: %w", key, err)
+		}
+	}
+	c.Set(key, value)
+	return nil
+}
+
+// Remove deletes the key from both the cache and the store, in the same way as Write stores it.
+// Without a store Remove is the same as Delete.
+func (c *TypedCache[K, V]) Remove(ctx context.Context, key K) error {
+	defer c.writeLocks.lock(key)()
+	switch {
+	case c.behind != nil:
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.behind.queue(key, &pendingWrite[V]{deleted: true})
+	case c.backing != nil:
+		if err := c.backing.Delete(ctx, key); err != nil {
+			return fmt.Errorf("cache: delete %v: %w", key, err)
+		}
+	}
+	c.Delete(key)
+	return nil
+}
+
+// Flush applies every change queued by write-behind to the store and returns the errors of
+// those that failed. Failed changes stay queued for retry like in a background flush.
+// Without write-behind it does nothing.
+func (c *TypedCache[K, V]) Flush(ctx context.Context) error {
+	if c.behind == nil {
+		return nil
+	}
+	return c.behind.flush(ctx, c)
+}
+
+// loadFromStore is the loader used by Fetch.
+func (c *TypedCache[K, V]) loadFromStore(ctx context.Context, key K) (V, error) {
+	if c.behind != nil {
+		if p, ok := c.behind.lookup(key); ok {
+			if p.deleted {
+				var zero V
+				return zero, ErrNotFound
+			}
+			return p.value, nil
+		}
+	}
+	if c.backing == nil {
+		var zero V
+		return zero, ErrNotFound
+	}
+	return c.backing.Load(ctx, key)
+}
+
+// queue records the latest change of the key, replacing any change not yet flushed.
+func (w *writeBehind[K, V]) queue(key K, p *pendingWrite[V]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending == nil {
+		w.pending = make(map[K]*pendingWrite[V])
+	}
+	w.pending[key] = p
+}
+
+// lookup returns the queued change of the key, if any.
+func (w *writeBehind[K, V]) lookup(key K) (pendingWrite[V], bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p, ok := w.pending[key]
+	if !ok {
+		return pendingWrite[V]{}, false
+	}
+	return *p, true
+}
+
+// flush applies the queued changes to the cache's store. The changes stay queued, and so
+// visible to lookup, until they have been applied; a change replaced meanwhile by a newer one
+// is left for the next flush. A failed change is retried by later flushes until it has been
+// attempted retries+1 times.
+func (w *writeBehind[K, V]) flush(ctx context.Context, c *TypedCache[K, V]) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	batch := maps.Clone(w.pending)
+	w.mu.Unlock()
+
+	var errs []error
+	for key, p := range batch {
+		var err error
+		if p.deleted {
+			err = c.backing.Delete(ctx, key)
+		} else {
+			err = c.backing.Save(ctx, key, p.value)
+		}
+
+		w.mu.Lock()
+		current := w.pending[key] == p
+		if err == nil {
+			if current {
+				delete(w.pending, key)
+			}
+			w.mu.Unlock()
+			continue
+		}
+		// A change replaced meanwhile is superseded and not retried.
+		p.attempts++
+		gaveUp := current && p.attempts > w.retries
+		if gaveUp {
+			delete(w.pending, key)
+		}
+		attempts := p.attempts
+		w.mu.Unlock()
+
+		err = fmt.Errorf("cache: write-behind %v: %w", key, err)
+		errs = append(errs, err)
+		if gaveUp {
+			c.errorHandler(fmt.Errorf("%w; giving up after %d attempts", err, attempts))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapStore is an in-memory Store that counts its calls and can be made to fail.
+type mapStore struct {
+	mu     sync.Mutex
+	data   map[string]int
+	loads  int
+	saves  int
+	fail   error
+	failed int
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{data: make(map[string]int)}
+}
+
+func (s *mapStore) Load(ctx context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	v, ok := s.data[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *mapStore) Save(ctx context.Context, key string, value int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		s.failed++
+		return s.fail
+	}
+	s.saves++
+	s.data[key] = value
+	return nil
+}
+
+func (s *mapStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		s.failed++
+		return s.fail
+	}
+	delete(s.data, key)
+	return nil
+}
+
+func (s *mapStore) get(key string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func TestReadThrough(t *testing.T) {
+	store := newMapStore()
+	store.data["a"] = 1
+	c := NewTyped[string, int](WithStore[string, int](store))
+	defer c.Close()
+	ctx := context.Background()
+
+	for range 2 {
+		if v, err := c.Fetch(ctx, "a"); err != nil || v != 1 {
+			t.Errorf("Fetch(a) = %v, %v, want 1, nil", v, err)
+		}
+	}
+	if store.loads != 1 {
+		t.Errorf("store loads = %d, want 1", store.loads)
+	}
+	if _, err := c.Fetch(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	store := newMapStore()
+	c := NewTyped[string, int](WithStore[string, int](store))
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Write(ctx, "a", 1); err != nil {
+		t.Fatalf("Write(a) error = %v", err)
+	}
+	if v, ok := store.get("a"); !ok || v != 1 {
+		t.Errorf("store a = %v, %v, want 1, true", v, ok)
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+
+	store.fail = errors.New("db down")
+	if err := c.Write(ctx, "a", 2); !errors.Is(err, store.fail) {
+		t.Errorf("Write(a) error = %v, want the store's error", err)
+	}
+	if v, _ := c.Get("a"); v != 1 {
+		t.Errorf("Get(a) after failed Write = %v, want unchanged 1", v)
+	}
+	if err := c.Remove(ctx, "a"); err == nil {
+		t.Errorf("Remove(a) error = nil, want the store's error")
+	}
+
+	store.fail = nil
+	if err := c.Remove(ctx, "a"); err != nil {
+		t.Errorf("Remove(a) error = %v", err)
+	}
+	if _, ok := store.get("a"); ok {
+		t.Errorf("store still holds a after Remove")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(a) hit after Remove")
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	store := newMapStore()
+	store.data["gone"] = 1
+	var reported []error
+	c := NewTyped[string, int](
+		WithStore[string, int](store),
+		WithWriteBehind(time.Hour, 1),
+		WithErrorHandler(func(err error) { reported = append(reported, err) }),
+	)
+	ctx := context.Background()
+
+	for i := range 5 {
+		c.Write(ctx, "a", i)
+	}
+	c.Remove(ctx, "gone")
+	if _, ok := store.get("a"); ok {
+		t.Errorf("store holds a before Flush, want the write queued")
+	}
+	c.Delete("gone")
+	if _, err := c.Fetch(ctx, "gone"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(gone) error = %v, want ErrNotFound from the queued delete", err)
+	}
+
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if v, ok := store.get("a"); !ok || v != 4 || store.saves != 1 {
+		t.Errorf("store a = %v, %v after %d saves, want the latest value 4 saved once", v, ok, store.saves)
+	}
+	if _, ok := store.get("gone"); ok {
+		t.Errorf("store still holds gone after Flush")
+	}
+
+	store.fail = errors.New("db down")
+	c.Write(ctx, "b", 1)
+	if err := c.Flush(ctx); !errors.Is(err, store.fail) {
+		t.Errorf("Flush() error = %v, want the store's error", err)
+	}
+	if len(reported) != 0 {
+		t.Errorf("reported %v after the first failure, want the write kept for retry", reported)
+	}
+	c.Close()
+	if store.failed != 2 || len(reported) == 0 {
+		t.Errorf("store failures = %d, reported = %v, want the retry on Close to give up and report", store.failed, reported)
+	}
+}
+
+// blockingStore is a mapStore whose saves wait until release is closed.
+type blockingStore struct {
+	*mapStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(ctx context.Context, key string, value int) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.mapStore.Save(ctx, key, value)
+}
+
+func TestWriteBehindVisibleDuringFlush(t *testing.T) {
+	store := &blockingStore{mapStore: newMapStore(), saving: make(chan struct{}), release: make(chan struct{})}
+	store.data["a"] = 1
+	c := NewTyped[string, int](WithStore[string, int](store), WithWriteBehind(time.Hour, 0))
+	ctx := context.Background()
+
+	c.Write(ctx, "a", 2)
+	c.Delete("a")
+	flushed := make(chan error)
+	go func() { flushed <- c.Flush(ctx) }()
+	<-store.saving
+	if v, err := c.Fetch(ctx, "a"); err != nil || v != 2 {
+		t.Errorf("Fetch(a) during flush = %v, %v, want the queued value 2", v, err)
+	}
+
+	// A change queued while its key is being saved is kept for the next flush.
+	c.Write(ctx, "a", 3)
+	close(store.release)
+	if err := <-flushed; err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	c.Delete("a")
+	if v, err := c.Fetch(ctx, "a"); err != nil || v != 3 {
+		t.Errorf("Fetch(a) after flush = %v, %v, want the newer queued value 3", v, err)
+	}
+	go func() {
+		for range store.saving {
+		}
+	}()
+	c.Close()
+	close(store.saving)
+	if v, _ := store.get("a"); v != 3 {
+		t.Errorf("store a = %v after Close, want 3", v)
+	}
+}
+
+func TestWriteConcurrent(t *testing.T) {
+	store := newMapStore()
+	c := NewTyped[string, int](WithStore[string, int](store))
+	defer c.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Write(ctx, "a", i)
+		}()
+	}
+	wg.Wait()
+	got, _ := c.Get("a")
+	if want, _ := store.get("a"); got != want {
+		t.Errorf("cache a = %v, store a = %v, want them equal", got, want)
+	}
+}
@@ -0,0 +1,348 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"math"
+	"slices"
+	"sort"
+)
+
+// ErrWrongType is returned by the list, hash, set and sorted set operations when the key
+// holds a value of another kind.
+var ErrWrongType = errors.New("cache: operation against a key holding the wrong kind of value")
+
+// ErrNaNScore is returned by ZAdd for a NaN score, which cannot be ordered.
+var ErrNaNScore = errors.New("cache: score is not a number (NaN)")
+
+// ScoredMember is a member of a sorted set together with its score.
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+func init() {
+	gob.Register(&list{})
+	gob.Register(&hash{})
+	gob.Register(&set{})
+	gob.Register(&sortedSet{})
+}
+
+// structure is implemented by the values of lists, hashes, sets and sorted sets. Their
+// operations change them in place under the cache lock, so they are copied with clone
+// before they are handed out and an operation that empties one deletes its key.
+type structure interface {
+	clone() any
+	len() int
+}
+
+// detach returns a copy of v if it is a structure, and v otherwise. Values leaving the cache
+// lock go through it so that readers never see a structure change under them. Only interface
+// value types can hold structures, so for the others v is returned without being boxed.
+func (c *TypedCache[K, V]) detach(v V) V {
+	if !c.structures {
+		return v
+	}
+	if s, ok := any(v).(structure); ok {
+		return s.clone().(V)
+	}
+	return v
+}
+
+// list holds its items from right to left, so that LPush appends and RPop reslices.
+type list struct {
+	Items []interface{}
+}
+
+func (l *list) clone() any { return &list{Items: slices.Clone(l.Items)} }
+func (l *list) len() int   { return len(l.Items) }
+
+type hash struct {
+	Fields map[string]interface{}
+}
+
+func (h *hash) clone() any { return &hash{Fields: cloneMap(h.Fields)} }
+func (h *hash) len() int   { return len(h.Fields) }
+
+type set struct {
+	Members map[string]bool
+}
+
+func (s *set) clone() any { return &set{Members: cloneMap(s.Members)} }
+func (s *set) len() int   { return len(s.Members) }
+
+// sortedSet keeps its members ordered by score, then by member, next to a map of the scores.
+type sortedSet struct {
+	Scores  map[string]float64
+	Ordered []ScoredMember
+}
+
+func (z *sortedSet) clone() any {
+	return &sortedSet{Scores: cloneMap(z.Scores), Ordered: slices.Clone(z.Ordered)}
+}
+func (z *sortedSet) len() int { return len(z.Ordered) }
+
+// search returns the position of the member with the score in z.Ordered, or where it would be inserted.
+func (z *sortedSet) search(score float64, member string) int {
+	return sort.Search(len(z.Ordered), func(i int) bool {
+		m := z.Ordered[i]
+		return m.Score > score || (m.Score == score && m.Member >= member)
+	})
+}
+
+func cloneMap[T any](m map[string]T) map[string]T {
+	c := make(map[string]T, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// LPush inserts the values at the head of the list stored under the key, one after the other,
+// and returns the length of the list. A missing key is created with the default TTL.
+func (c *Cache) LPush(key string, values ...interface{}) (int, error) {
+	var n int
+	err := mutate(c, key, func() *list { return &list{} }, func(l *list) bool {
+		l.Items = append(l.Items, values...)
+		n = len(l.Items)
+		return len(values) > 0
+	})
+	return n, err
+}
+
+// RPop removes and returns the last value of the list stored under the key, reporting false
+// if the key is missing. Removing the last value deletes the key.
+func (c *Cache) RPop(key string) (interface{}, bool, error) {
+	var (
+		v  interface{}
+		ok bool
+	)
+	err := mutate(c, key, nil, func(l *list) bool {
+		v, ok = l.Items[0], true
+		l.Items[0] = nil
+		l.Items = l.Items[1:]
+		return true
+	})
+	return v, ok, err
+}
+
+// LRange returns the values of the list stored under the key from index start to stop,
+// both included. Negative indexes count from the end of the list, -1 being the last value.
+// A missing key is an empty list.
+func (c *Cache) LRange(key string, start, stop int) ([]interface{}, error) {
+	var values []interface{}
+	err := read(c, key, func(l *list) {
+		n := len(l.Items)
+		if start < 0 {
+			start = max(n+start, 0)
+		}
+		if stop < 0 {
+			stop = n + stop
+		}
+		for i := start; i <= min(stop, n-1); i++ {
+			values = append(values, l.Items[n-1-i])
+		}
+	})
+	return values, err
+}
+
+// HSet sets the field of the hash stored under the key and reports whether the field is new.
+// A missing key is created with the default TTL.
+func (c *Cache) HSet(key, field string, value interface{}) (bool, error) {
+	var created bool
+	err := mutate(c, key, func() *hash { return &hash{Fields: make(map[string]interface{})} }, func(h *hash) bool {
+		_, exists := h.Fields[field]
+		created = !exists
+		h.Fields[field] = value
+		return true
+	})
+	return created, err
+}
+
+// HGet returns the field of the hash stored under the key and whether it exists.
+func (c *Cache) HGet(key, field string) (interface{}, bool, error) {
+	var (
+		v  interface{}
+		ok bool
+	)
+	err := read(c, key, func(h *hash) {
+		v, ok = h.Fields[field]
+	})
+	return v, ok, err
+}
+
+// HGetAll returns a copy of the fields of the hash stored under the key.
+// A missing key is an empty hash.
+func (c *Cache) HGetAll(key string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	err := read(c, key, func(h *hash) {
+		fields = cloneMap(h.Fields)
+	})
+	return fields, err
+}
+
+// SAdd adds the members to the set stored under the key and returns how many were not
+// already in it. A missing key is created with the default TTL.
+func (c *Cache) SAdd(key string, members ...string) (int, error) {
+	var added int
+	err := mutate(c, key, func() *set { return &set{Members: make(map[string]bool)} }, func(s *set) bool {
+		for _, m := range members {
+			if !s.Members[m] {
+				s.Members[m] = true
+				added++
+			}
+		}
+		return added > 0
+	})
+	return added, err
+}
+
+// SMembers returns the members of the set stored under the key in sorted order.
+// A missing key is an empty set.
+func (c *Cache) SMembers(key string) ([]string, error) {
+	var members []string
+	err := read(c, key, func(s *set) {
+		for m := range s.Members {
+			members = append(members, m)
+		}
+	})
+	slices.Sort(members)
+	return members, err
+}
+
+// SIsMember reports whether the member is in the set stored under the key.
+func (c *Cache) SIsMember(key, member string) (bool, error) {
+	var ok bool
+	err := read(c, key, func(s *set) {
+		ok = s.Members[member]
+	})
+	return ok, err
+}
+
+// ZAdd adds the member to the sorted set stored under the key with the given score, or
+// updates its score, and reports whether the member is new. A missing key is created with
+// the default TTL. A NaN score is rejected with ErrNaNScore.
+func (c *Cache) ZAdd(key string, score float64, member string) (bool, error) {
+	if math.IsNaN(score) {
+		return false, ErrNaNScore
+	}
+	var created bool
+	err := mutate(c, key, func() *sortedSet { return &sortedSet{Scores: make(map[string]float64)} }, func(z *sortedSet) bool {
+		old, exists := z.Scores[member]
+		if exists {
+			if old == score {
+				return false
+			}
+			i := z.search(old, member)
+			z.Ordered = slices.Delete(z.Ordered, i, i+1)
+		}
+		created = !exists
+		z.Scores[member] = score
+		z.Ordered = slices.Insert(z.Ordered, z.search(score, member), ScoredMember{Member: member, Score: score})
+		return true
+	})
+	return created, err
+}
+
+// ZRangeByScore returns the members of the sorted set stored under the key whose score is
+// between min and max, both included, ordered by score and then by member.
+// A missing key is an empty sorted set.
+func (c *Cache) ZRangeByScore(key string, min, max float64) ([]ScoredMember, error) {
+	var members []ScoredMember
+	err := read(c, key, func(z *sortedSet) {
+		i := sort.Search(len(z.Ordered), func(i int) bool { return z.Ordered[i].Score >= min })
+		for ; i < len(z.Ordered) && z.Ordered[i].Score <= max; i++ {
+			members = append(members, z.Ordered[i])
+		}
+	})
+	return members, err
+}
+
+// mutate calls fn with the structure stored under the key, or with a new one from create if
+// the key is missing; with a nil create a missing key is left alone. fn reports whether it
+// changed the structure. A changed structure is written like any other value, except that an
+// existing one keeps its TTL and tags, and an empty one deletes the key.
+func mutate[T structure](c *Cache, key string, create func() T, fn func(v T) bool) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.find(key)
+	if ok && e.expired(c.clock.Now()) {
+		ok = false
+	}
+	var v T
+	switch {
+	case ok:
+		if v, ok = e.value.(T); !ok {
+			return ErrWrongType
+		}
+	case create == nil:
+		return nil
+	default:
+		v = create()
+	}
+
+	switch {
+	case !fn(v):
+	case v.len() == 0:
+		if ok {
+			c.removeEntry(e, ReasonDeleted)
+		}
+	case ok:
+		c.modified(e)
+	default:
+		c.set(key, v, c.deadline(DefaultExpiration))
+	}
+	return nil
+}
+
+// read calls fn with the structure stored under the key. A missing key is not an error and
+// fn is not called. fn runs under the cache lock and must copy what it returns.
+func read[T structure](c *Cache, key string, fn func(v T)) error {
+	if c.bounded() {
+		c.mu.Lock()
+		defer c.unlock()
+	} else {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) {
+		c.countLookup(false)
+		return nil
+	}
+	v, ok := e.value.(T)
+	if !ok {
+		return ErrWrongType
+	}
+	c.countLookup(true)
+	if c.bounded() {
+		c.lru.moveToFront(e)
+	}
+	fn(v)
+	return nil
+}
+
+// modified records that the value of a live entry was changed in place: like a write it gets
+// a new version and cost, is logged and published, and entries over the limits are evicted.
+// The caller must hold c.mu.
+func (c *TypedCache[K, V]) modified(e *entry[K, V]) {
+	delete(c.failures, e.key)
+	c.stats.sets.Add(1)
+
+	cost := c.entryCost(e.key, e.value)
+	if c.maxCost > 0 && cost > c.maxCost {
+		c.dropEntry(e, ReasonEvicted, false)
+		return
+	}
+	c.stats.cost.Add(cost - e.cost)
+	e.cost = cost
+
+	c.version++
+	e.version = c.version
+	e.loaded = false
+	c.lru.moveToFront(e)
+	c.written(e)
+	c.evictOverCapacity()
+}
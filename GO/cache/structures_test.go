@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLists(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	if n, err := c.LPush("l", "c", "b", "a"); n != 3 || err != nil {
+		t.Fatalf("LPush() = %d, %v, want 3, nil", n, err)
+	}
+	c.LPush("l", "z")
+
+	testCases := []struct {
+		start, stop int
+		want        []interface{}
+	}{
+		{0, -1, []interface{}{"z", "a", "b", "c"}},
+		{1, 2, []interface{}{"a", "b"}},
+		{-2, -1, []interface{}{"b", "c"}},
+		{-100, 0, []interface{}{"z"}},
+		{2, 100, []interface{}{"b", "c"}},
+		{3, 1, nil},
+	}
+	for _, tc := range testCases {
+		got, err := c.LRange("l", tc.start, tc.stop)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("LRange(%d, %d) = %v, %v, want %v", tc.start, tc.stop, got, err, tc.want)
+		}
+	}
+
+	for _, want := range []string{"c", "b", "a", "z"} {
+		if v, ok, err := c.RPop("l"); v != want || !ok || err != nil {
+			t.Errorf("RPop() = %v, %v, %v, want %s", v, ok, err, want)
+		}
+	}
+	if _, ok := c.Get("l"); ok {
+		t.Errorf("popping the last value kept the key")
+	}
+	if _, ok, err := c.RPop("l"); ok || err != nil {
+		t.Errorf("RPop() on a missing key = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestHashesAndSets(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	if created, _ := c.HSet("h", "a", 1); !created {
+		t.Errorf("HSet() of a new field = false, want true")
+	}
+	if created, _ := c.HSet("h", "a", 2); created {
+		t.Errorf("HSet() of an existing field = true, want false")
+	}
+	c.HSet("h", "b", "x")
+	if v, ok, _ := c.HGet("h", "a"); v != 2 || !ok {
+		t.Errorf("HGet(a) = %v, %v, want 2, true", v, ok)
+	}
+	if _, ok, _ := c.HGet("h", "missing"); ok {
+		t.Errorf("HGet() of a missing field reported it present")
+	}
+	all, _ := c.HGetAll("h")
+	if want := map[string]interface{}{"a": 2, "b": "x"}; !reflect.DeepEqual(all, want) {
+		t.Errorf("HGetAll() = %v, want %v", all, want)
+	}
+	all["c"] = 3
+	if _, ok, _ := c.HGet("h", "c"); ok {
+		t.Errorf("changing the map returned by HGetAll changed the hash")
+	}
+
+	if n, _ := c.SAdd("s", "b", "a", "b"); n != 2 {
+		t.Errorf("SAdd() = %d, want 2", n)
+	}
+	if n, _ := c.SAdd("s", "a", "c"); n != 1 {
+		t.Errorf("SAdd() = %d, want 1", n)
+	}
+	if got, _ := c.SMembers("s"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("SMembers() = %v, want [a b c]", got)
+	}
+	if ok, _ := c.SIsMember("s", "b"); !ok {
+		t.Errorf("SIsMember(b) = false, want true")
+	}
+	if ok, _ := c.SIsMember("s", "z"); ok {
+		t.Errorf("SIsMember(z) = true, want false")
+	}
+}
+
+func TestSortedSets(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	c.ZAdd("z", 3, "c")
+	c.ZAdd("z", 1, "a")
+	c.ZAdd("z", 2, "b2")
+	c.ZAdd("z", 2, "b1")
+	if created, _ := c.ZAdd("z", 5, "a"); created {
+		t.Errorf("ZAdd() of an existing member = true, want false")
+	}
+	for range 2 {
+		if _, err := c.ZAdd("z", math.NaN(), "c"); !errors.Is(err, ErrNaNScore) {
+			t.Errorf("ZAdd(NaN) error = %v, want ErrNaNScore", err)
+		}
+	}
+
+	testCases := []struct {
+		min, max float64
+		want     []ScoredMember
+	}{
+		{0, 10, []ScoredMember{{"b1", 2}, {"b2", 2}, {"c", 3}, {"a", 5}}},
+		{2, 2, []ScoredMember{{"b1", 2}, {"b2", 2}}},
+		{2.5, 4, []ScoredMember{{"c", 3}}},
+		{6, 10, nil},
+	}
+	for _, tc := range testCases {
+		got, err := c.ZRangeByScore("z", tc.min, tc.max)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ZRangeByScore(%v, %v) = %v, %v, want %v", tc.min, tc.max, got, err, tc.want)
+		}
+	}
+}
+
+func TestStructuresWrongTypeAndTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := New(WithClock(clock), WithDefaultTTL(time.Minute))
+	defer c.Close()
+
+	c.Set("string", "x")
+	if _, err := c.LPush("string", 1); !errors.Is(err, ErrWrongType) {
+		t.Errorf("LPush() on a string error = %v, want ErrWrongType", err)
+	}
+	c.SAdd("set", "a")
+	if _, err := c.HGetAll("set"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("HGetAll() on a set error = %v, want ErrWrongType", err)
+	}
+
+	c.Expire("set", time.Hour)
+	c.SAdd("set", "b")
+	if ttl, _ := c.TTL("set"); ttl != time.Hour {
+		t.Errorf("TTL() after SAdd = %v, want the existing %v", ttl, time.Hour)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if n, _ := c.SAdd("set", "c"); n != 1 {
+		t.Errorf("SAdd() on an expired set = %d, want 1", n)
+	}
+	if got, _ := c.SMembers("set"); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("SMembers() after expiry = %v, want [c]", got)
+	}
+	if ttl, _ := c.TTL("set"); ttl != time.Minute {
+		t.Errorf("TTL() of a recreated set = %v, want the default %v", ttl, time.Minute)
+	}
+}
+
+func TestStructuresAreCopiedOut(t *testing.T) {
+	c := New()
+	defer c.Close()
+	sub := c.Subscribe(10)
+	defer sub.Close()
+
+	c.LPush("l", 1)
+	v, _ := c.Get("l")
+	c.LPush("l", 2)
+	ev := <-sub.C
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	c.LPush("l", 3)
+
+	if got := v.(*list).len(); got != 1 {
+		t.Errorf("list returned by Get changed to %d items, want 1", got)
+	}
+	if got := ev.Value.(*list).len(); got != 1 {
+		t.Errorf("list published by the first LPush changed to %d items, want 1", got)
+	}
+
+	restored := New()
+	defer restored.Close()
+	if err := restored.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := restored.LRange("l", 0, -1); !reflect.DeepEqual(got, []interface{}{2, 1}) {
+		t.Errorf("restored LRange() = %v, want [2 1]", got)
+	}
+}
+
+func TestStructuresConcurrent(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				c.LPush("l", j)
+				c.HSet("h", fmt.Sprint(i, "/", j), j)
+				c.SAdd("s", fmt.Sprint(j))
+				c.ZAdd("z", float64(j), fmt.Sprint(i, "/", j))
+				c.LRange("l", 0, 10)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := c.LRange("l", 0, -1); len(got) != 800 {
+		t.Errorf("list has %d values, want 800", len(got))
+	}
+	if got, _ := c.HGetAll("h"); len(got) != 800 {
+		t.Errorf("hash has %d fields, want 800", len(got))
+	}
+	if got, _ := c.SMembers("s"); len(got) != 100 {
+		t.Errorf("set has %d members, want 100", len(got))
+	}
+	if got, _ := c.ZRangeByScore("z", 0, 99); len(got) != 800 {
+		t.Errorf("sorted set has %d members, want 800", len(got))
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"slices"
+	"strings"
+)
+
+// InvalidateTag removes every entry carrying the tag and returns how many were removed.
+// It only visits the tagged entries.
+func (c *TypedCache[K, V]) InvalidateTag(tag string) int {
+	c.mu.Lock()
+	defer c.unlock()
+
+	removed := 0
+	for key := range c.tags[tag] {
+		if e, ok := c.store[key]; ok {
+			c.removeEntry(e, ReasonDeleted)
+			removed++
+		}
+	}
+	if c.disk != nil {
+		removed += c.disk.dropFunc(c.clock.Now(), func(key K, de diskEntry) bool { return de.hasTag(tag) })
+	}
+	return removed
+}
+
+// Tags returns the tags attached to the key, or nil if the key is missing or untagged.
+func (c *TypedCache[K, V]) Tags(key K) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	if e, ok := c.store[key]; ok && !e.expired(now) {
+		return slices.Clone(e.tags)
+	}
+	if de, ok := c.disk.peek(key, now); ok {
+		return slices.Clone(de.tags)
+	}
+	return nil
+}
+
+// retag replaces the entry's tags and updates the tag index. The caller must hold c.mu.
+func (c *TypedCache[K, V]) retag(e *entry[K, V], tags []string) {
+	if slices.Equal(e.tags, tags) {
+		return
+	}
+
+	for _, tag := range e.tags {
+		keys := c.tags[tag]
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+
+	e.tags = nil
+	for _, tag := range tags {
+		if slices.Contains(e.tags, tag) {
+			continue
+		}
+		e.tags = append(e.tags, tag)
+
+		if c.tags == nil {
+			c.tags = make(map[string]map[K]struct{})
+		}
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			c.tags[tag] = keys
+		}
+		keys[e.key] = struct{}{}
+	}
+}
+
+// DeletePrefix removes every entry whose key starts with prefix and returns how many were
+// removed. With WithKeyIndex it visits only the matching keys; otherwise it scans the cache.
+func (c *Cache) DeletePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.unlock()
+
+	var matched []string
+	if c.index != nil {
+		for n := c.index.seek(prefix); n != nil && strings.HasPrefix(n.key, prefix); n = n.next[0] {
+			matched = append(matched, n.key)
+		}
+	} else {
+		for key := range c.store {
+			if strings.HasPrefix(key, prefix) {
+				matched = append(matched, key)
+			}
+		}
+	}
+
+	for _, key := range matched {
+		c.removeEntry(c.store[key], ReasonDeleted)
+	}
+	removed := len(matched)
+	if c.disk != nil {
+		removed += c.disk.dropFunc(c.clock.Now(), func(key string, de diskEntry) bool { return strings.HasPrefix(key, prefix) })
+	}
+	return removed
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	c.SetWith("user:1", "profile", WithTags("user:1"))
+	c.SetWith("user:1:friends", 3, WithTags("user:1", "friends"))
+	c.SetWith("user:2", "profile", WithTags("user:2"))
+	c.Incr("user:1:friends", 1)
+
+	if got := c.Tags("user:1:friends"); !slices.Equal(got, []string{"user:1", "friends"}) {
+		t.Errorf("Tags(user:1:friends) after Incr = %v, want [user:1 friends]", got)
+	}
+
+	if n := c.InvalidateTag("user:1"); n != 2 {
+		t.Errorf("InvalidateTag(user:1) = %d, want 2", n)
+	}
+	for _, key := range []string{"user:1", "user:1:friends"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("Get(%q) hit after InvalidateTag", key)
+		}
+	}
+	if _, ok := c.Get("user:2"); !ok {
+		t.Errorf("Get(user:2) missed, want untouched entry")
+	}
+
+	c.SetWith("user:2", "profile", WithTags("old"))
+	c.Set("user:2", "replaced")
+	if n := c.InvalidateTag("old"); n != 0 {
+		t.Errorf("InvalidateTag(old) after plain Set = %d, want 0", n)
+	}
+	if len(c.tags) != 0 {
+		t.Errorf("tag index = %v, want empty", c.tags)
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		t.Run("indexed="+strconv.FormatBool(indexed), func(t *testing.T) {
+			var opts []Option
+			if indexed {
+				opts = append(opts, WithKeyIndex())
+			}
+			c := New(opts...)
+			defer c.Close()
+
+			for _, key := range []string{"user:1", "user:10", "user", "users", "session:1"} {
+				c.Set(key, 1)
+			}
+			if n := c.DeletePrefix("user:"); n != 2 {
+				t.Errorf("DeletePrefix(user:) = %d, want 2", n)
+			}
+
+			keys := c.Keys()
+			sort.Strings(keys)
+			if want := []string{"session:1", "user", "users"}; !slices.Equal(keys, want) {
+				t.Errorf("Keys() = %v, want %v", keys, want)
+			}
+		})
+	}
+}
+
+func TestKeyIndexOrder(t *testing.T) {
+	ix := newKeyIndex[int]()
+	want := map[int]bool{}
+	for i := 0; i < 2000; i++ {
+		k := rand.IntN(500)
+		if rand.IntN(3) == 0 {
+			ix.remove(k)
+			delete(want, k)
+		} else {
+			ix.insert(k)
+			want[k] = true
+		}
+	}
+
+	var got []int
+	for n := ix.seek(0); n != nil; n = n.next[0] {
+		got = append(got, n.key)
+	}
+	var expected []int
+	for k := range want {
+		expected = append(expected, k)
+	}
+	sort.Ints(expected)
+	if !slices.Equal(got, expected) {
+		t.Errorf("index keys = %v, want %v", got, expected)
+	}
+
+	if newKeyIndex[struct{ a int }]() != nil {
+		t.Errorf("newKeyIndex for a struct key = non-nil, want nil")
+	}
+}
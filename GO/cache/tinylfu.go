@@ -0,0 +1,145 @@
+package cache
+
+import "hash/maphash"
+
+// Parameters of the TinyLFU frequency sketch.
+const (
+	sketchDepth      = 4  // rows of the count-min sketch
+	sketchMaxCount   = 15 // counters saturate like 4-bit counters
+	minSketchSamples = 1024
+	// defaultSketchSamples sizes the sketch of a cache bounded by cost alone,
+	// whose entry count is unknown.
+	defaultSketchSamples = 1 << 16
+)
+
+// tinyLFU estimates how often keys were accessed recently, for deciding whether a new key is
+// worth evicting others for. A doorkeeper Bloom filter absorbs the first access of every key
+// so that one-off keys never reach the count-min sketch. After samples accesses all counters
+// are halved and the doorkeeper is cleared, so the estimates follow changes in popularity.
+type tinyLFU[K comparable] struct {
+	seed    maphash.Seed
+	rows    [sketchDepth][]uint8
+	door    []uint64 // doorkeeper bit set
+	mask    uint64
+	samples int
+	added   int
+}
+
+// newTinyLFU returns a sketch sized for about samples accesses between resets.
+func newTinyLFU[K comparable](samples int) *tinyLFU[K] {
+	samples = max(samples, minSketchSamples)
+	width := 1
+	for width < samples {
+		width <<= 1
+	}
+
+	f := &tinyLFU[K]{
+		seed:    maphash.MakeSeed(),
+		door:    make([]uint64, width/64),
+		mask:    uint64(width - 1),
+		samples: samples,
+	}
+	for i := range f.rows {
+		f.rows[i] = make([]uint8, width)
+	}
+	return f
+}
+
+// record counts an access to the key.
+func (f *tinyLFU[K]) record(key K) {
+	h := maphash.Comparable(f.seed, key)
+	if !f.admitDoor(h) {
+		for i := range f.rows {
+			if c := &f.rows[i][f.slot(h, i)]; *c < sketchMaxCount {
+				*c++
+			}
+		}
+	}
+
+	if f.added++; f.added >= f.samples {
+		f.reset()
+	}
+}
+
+// estimate returns the approximate number of recent accesses to the key.
+func (f *tinyLFU[K]) estimate(key K) int {
+	h := maphash.Comparable(f.seed, key)
+	n := sketchMaxCount
+	for i := range f.rows {
+		n = min(n, int(f.rows[i][f.slot(h, i)]))
+	}
+	if f.doorHas(h) {
+		n++
+	}
+	return n
+}
+
+// slot returns the counter of the hash in row i, using double hashing to derive independent
+// positions from a single 64-bit hash.
+func (f *tinyLFU[K]) slot(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|h<<32|1)) & f.mask
+}
+
+// admitDoor adds the hash to the doorkeeper and reports whether it was absent.
+func (f *tinyLFU[K]) admitDoor(h uint64) bool {
+	absent := false
+	for i := range 2 {
+		bit := f.slot(h, i+sketchDepth)
+		if f.door[bit/64]&(1<<(bit%64)) == 0 {
+			f.door[bit/64] |= 1 << (bit % 64)
+			absent = true
+		}
+	}
+	return absent
+}
+
+// doorHas reports whether the hash is in the doorkeeper.
+func (f *tinyLFU[K]) doorHas(h uint64) bool {
+	for i := range 2 {
+		bit := f.slot(h, i+sketchDepth)
+		if f.door[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reset ages the sketch by halving every counter and clearing the doorkeeper.
+func (f *tinyLFU[K]) reset() {
+	for i := range f.rows {
+		for j := range f.rows[i] {
+			f.rows[i][j] >>= 1
+		}
+	}
+	clear(f.door)
+	f.added = 0
+}
+
+// admit reports whether a new entry for the key with the given cost should be stored when
+// doing so requires evicting entries. It compares the key's estimated frequency with that of
+// every live entry that would be evicted, starting from the least recently used one, and
+// rejects the key if any of them is more popular. The caller must hold c.mu.
+func (c *TypedCache[K, V]) admit(key K, cost int64) bool {
+	excessEntries := 0
+	if c.maxEntries > 0 {
+		excessEntries = len(c.store) + 1 - c.maxEntries
+	}
+	var excessCost int64
+	if c.maxCost > 0 {
+		excessCost = c.stats.cost.Load() + cost - c.maxCost
+	}
+	if excessEntries <= 0 && excessCost <= 0 {
+		return true
+	}
+
+	freq := c.lfu.estimate(key)
+	now := c.clock.Now()
+	for victim := c.lru.back(); victim != nil && (excessEntries > 0 || excessCost > 0); victim = victim.prev {
+		if !victim.expired(now) && c.lfu.estimate(victim.key) > freq {
+			return false
+		}
+		excessEntries--
+		excessCost -= victim.cost
+	}
+	return true
+}
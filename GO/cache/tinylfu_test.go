@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	traceFile = flag.String("hitratio.trace", "", "file with one key per line, gzipped if named *.gz, replayed by BenchmarkHitRatio instead of the default traces")
+	traceSize = flag.Int("hitratio.size", 2000, "cache size used to replay -hitratio.trace")
+)
+
+func TestTinyLFUEstimate(t *testing.T) {
+	f := newTinyLFU[string](minSketchSamples)
+
+	if got := f.estimate("a"); got != 0 {
+		t.Errorf("estimate(a) before any access = %d, want 0", got)
+	}
+	f.record("a")
+	if got := f.estimate("a"); got != 1 {
+		t.Errorf("estimate(a) after the doorkeeper access = %d, want 1", got)
+	}
+	for range 5 {
+		f.record("a")
+	}
+	if got := f.estimate("a"); got != 6 {
+		t.Errorf("estimate(a) after 6 accesses = %d, want 6", got)
+	}
+	for range 20 {
+		f.record("a")
+	}
+	if got := f.estimate("a"); got != sketchMaxCount+1 {
+		t.Errorf("estimate(a) after 26 accesses = %d, want saturated %d", got, sketchMaxCount+1)
+	}
+
+	f.reset()
+	if got := f.estimate("a"); got != sketchMaxCount/2 {
+		t.Errorf("estimate(a) after reset = %d, want halved %d", got, sketchMaxCount/2)
+	}
+}
+
+func TestTinyLFUAdmission(t *testing.T) {
+	var rejected int
+	c := NewTyped[string, int](WithMaxEntries(10), WithTinyLFU(), WithOnEvict(func(key string, value int, reason Reason) {
+		if reason == ReasonRejected {
+			rejected++
+		}
+	}))
+	defer c.Close()
+
+	for i := range 10 {
+		key := "hot" + strconv.Itoa(i)
+		c.Set(key, i)
+		for range 3 {
+			c.Get(key)
+		}
+	}
+	for i := range 100 {
+		c.Set("scan"+strconv.Itoa(i), i)
+	}
+
+	for i := range 10 {
+		if _, ok := c.Get("hot" + strconv.Itoa(i)); !ok {
+			t.Errorf("Get(hot%d) missed, want frequently used keys to survive a scan", i)
+		}
+	}
+	if got := c.Stats().Rejections; got != 100 || rejected != 100 {
+		t.Errorf("Stats().Rejections = %d, OnEvict rejections = %d, want 100", got, rejected)
+	}
+
+	// A key requested often enough is admitted in place of the least popular entry.
+	for range 10 {
+		c.Get("new")
+	}
+	c.Set("new", 0)
+	if _, ok := c.Get("new"); !ok {
+		t.Errorf("Get(new) missed, want a popular new key admitted")
+	}
+}
+
+// hitRatioTrace is a recorded or generated sequence of keys replayed by BenchmarkHitRatio
+// through a cache holding size entries.
+type hitRatioTrace struct {
+	name string
+	keys []string
+	size int
+}
+
+// hitRatioTraces returns the traces replayed by BenchmarkHitRatio: the file named by
+// -hitratio.trace, or the recorded glimpse trace in testdata and a synthetic trace mixing a
+// Zipf-distributed working set with periodic scans of one-off keys.
+func hitRatioTraces(b *testing.B) []hitRatioTrace {
+	if *traceFile != "" {
+		return []hitRatioTrace{{"file", readTrace(b, *traceFile), *traceSize}}
+	}
+	return []hitRatioTrace{
+		{"glimpse", readTrace(b, "testdata/glimpse.lirs.gz"), 1000},
+		{"zipf-scan", zipfScanTrace(), 2000},
+	}
+}
+
+// readTrace reads a trace file holding one key per line, gzipped if its name ends in .gz.
+func readTrace(b *testing.B, name string) []string {
+	f, err := os.Open(name)
+	if err != nil {
+		b.Fatalf("opening trace: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			b.Fatalf("reading trace %s: %v", name, err)
+		}
+		r = zr
+	}
+	var keys []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		keys = append(keys, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		b.Fatalf("reading trace %s: %v", name, err)
+	}
+	return keys
+}
+
+// zipfScanTrace returns 500,000 Zipf-distributed lookups of 100,000 keys interrupted every
+// 25,000 lookups by a scan of 5,000 keys that are never read again.
+func zipfScanTrace() []string {
+	rng := rand.New(rand.NewPCG(1, 2))
+	zipf := rand.NewZipf(rng, 1.01, 1, 100_000)
+	keys := make([]string, 0, 600_000)
+	scanned := 0
+	for i := range 500_000 {
+		keys = append(keys, "k"+strconv.FormatUint(zipf.Uint64(), 10))
+		if i%25_000 == 0 {
+			for range 5_000 {
+				keys = append(keys, "scan"+strconv.Itoa(scanned))
+				scanned++
+			}
+		}
+	}
+	return keys
+}
+
+// BenchmarkHitRatio replays traces through a bounded cache with and without TinyLFU admission
+// and reports the resulting hit ratio. Run it with go test -run '^$' -bench HitRatio.
+func BenchmarkHitRatio(b *testing.B) {
+	for _, trace := range hitRatioTraces(b) {
+		for _, policy := range []struct {
+			name string
+			opts []Option
+		}{
+			{"LRU", nil},
+			{"TinyLFU", []Option{WithTinyLFU()}},
+		} {
+			b.Run(trace.name+"/"+policy.name, func(b *testing.B) {
+				var stats Stats
+				for range b.N {
+					c := NewTyped[string, struct{}](append([]Option{WithMaxEntries(trace.size)}, policy.opts...)...)
+					for _, key := range trace.keys {
+						if _, ok := c.Get(key); !ok {
+							c.Set(key, struct{}{})
+						}
+					}
+					stats = c.Stats()
+					c.Close()
+				}
+				b.ReportMetric(stats.HitRatio(), "hit-ratio")
+			})
+		}
+	}
+}
+
+func TestTinyLFUGetOrLoad(t *testing.T) {
+	c := NewTyped[string, int](WithMaxEntries(10), WithTinyLFU())
+	defer c.Close()
+	ctx := context.Background()
+	loader := func(ctx context.Context, key string) (int, error) { return len(key), nil }
+
+	for i := range 10 {
+		for range 5 {
+			if _, err := c.GetOrLoad(ctx, "hot"+strconv.Itoa(i), loader); err != nil {
+				t.Fatalf("GetOrLoad() error = %v", err)
+			}
+		}
+	}
+	for i := range 100 {
+		c.GetOrLoad(ctx, "scan"+strconv.Itoa(i), loader)
+	}
+
+	for i := range 10 {
+		if _, ok := c.Get("hot" + strconv.Itoa(i)); !ok {
+			t.Errorf("Get(hot%d) missed, want keys read through GetOrLoad to survive a scan", i)
+		}
+	}
+	if got := c.Stats().Rejections; got != 100 {
+		t.Errorf("Stats().Rejections = %d, want 100", got)
+	}
+}
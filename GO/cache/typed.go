@@ -0,0 +1,559 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TypedCache is a thread-safe in-memory key-value store with compile-time checked key and
+// value types, optional per-entry expiration and optional entry-count and cost limits
+// enforced with least-recently-used eviction. Values are stored unboxed.
+type TypedCache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	store      map[K]*entry[K, V]
+	lru        lruList[K, V]
+	maxEntries int
+	maxCost    int64
+	costFunc   func(key K, value V) int64
+	lfu        *tinyLFU[K] // admission policy, nil unless WithTinyLFU is set on a bounded cache
+	stats      counters
+	version    uint64 // last version assigned to an entry by a write
+	defaultTTL time.Duration
+	clock      Clock
+	janitor    *janitor
+	closeOnce  sync.Once
+	structures bool // V is an interface type, so values may be structures, see detach
+
+	index *keyIndex[K]              // ordered keys, nil unless WithKeyIndex is set
+	disk  *diskTier[K, V]           // demoted entries, nil until OpenDiskTier
+	tags  map[string]map[K]struct{} // keys carrying each tag
+
+	onEvict func(key K, value V, reason Reason)
+	evicted []evicted[K, V] // removals awaiting onEvict, see unlock
+	subs    map[*Subscription[K, V]]struct{}
+
+	messages broker // pub/sub channel subscriptions
+
+	backing    Store[K, V]        // nil unless WithStore is set
+	behind     *writeBehind[K, V] // nil unless WithWriteBehind is set
+	writeLocks keyLocks[K]        // serializes Write and Remove per key
+
+	snapshotPath string
+	snapshotter  *janitor
+	errorHandler func(error)
+	oplog        *opLog[K, V]
+
+	// GetOrLoad state: in-flight loads and remembered loader failures.
+	flightMu     sync.Mutex
+	calls        map[K]*call[V]
+	failures     map[K]failure
+	negativeTTL  time.Duration
+	errorTTL     time.Duration
+	refreshAhead time.Duration
+	staleGrace   time.Duration
+}
+
+// entry is a stored value together with its expiration deadline, version, tags and recency links.
+// The version changes on every write and serves as a compare-and-swap token.
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+	version   uint64
+	cost      int64
+	loaded    bool // stored by GetOrLoad, which may refresh it
+	tags      []string
+	prev      *entry[K, V]
+	next      *entry[K, V]
+}
+
+// expired reports whether the entry is past its deadline. A zero deadline never expires.
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return expiredAt(e.expiresAt, now)
+}
+
+// expiredAt reports whether a deadline has passed. A zero deadline never expires.
+func expiredAt(deadline, now time.Time) bool {
+	return !deadline.IsZero() && !now.Before(deadline)
+}
+
+// NewTyped creates an empty typed cache configured by the given options.
+// If a cleanup interval is configured, a background janitor removes expired entries
+// and the caller should call Close() to stop it, typically via defer.
+func NewTyped[K comparable, V any](opts ...Option) *TypedCache[K, V] {
+	o := newOptions(opts)
+	c := newTyped[K, V](o)
+
+	if o.cleanupInterval > 0 {
+		c.janitor = newJanitor(o.cleanupInterval)
+		go c.janitor.run(func() { c.DeleteExpired() })
+	}
+	if c.behind != nil {
+		c.behind.flusher = newJanitor(o.writeBehindInterval)
+		// Failed changes are retried by the next flush and reported once they are given up.
+		go c.behind.flusher.run(func() { c.Flush(context.Background()) })
+	}
+	if o.snapshotPath != "" && o.snapshotInterval > 0 {
+		c.snapshotter = newJanitor(o.snapshotInterval)
+		go c.snapshotter.run(c.autoSnapshot)
+	}
+	return c
+}
+
+// newTyped builds a cache from resolved options without starting any background work.
+func newTyped[K comparable, V any](o options) *TypedCache[K, V] {
+	c := &TypedCache[K, V]{
+		store:       make(map[K]*entry[K, V]),
+		maxEntries:  o.maxEntries,
+		maxCost:     o.maxCost,
+		defaultTTL:  o.defaultTTL,
+		clock:       o.clock,
+		negativeTTL: o.negativeTTL,
+		errorTTL:    o.errorTTL,
+
+		refreshAhead: o.refreshAhead,
+		staleGrace:   o.staleGrace,
+
+		snapshotPath: o.snapshotPath,
+		errorHandler: o.errorHandler,
+
+		structures: reflect.TypeFor[V]().Kind() == reflect.Interface,
+	}
+	if o.onEvict != nil {
+		fn, ok := o.onEvict.(func(K, V, Reason))
+		if !ok {
+			panic(fmt.Sprintf("cache: WithOnEvict callback %T does not match the cache's key and value types", o.onEvict))
+		}
+		c.onEvict = fn
+	}
+	if o.costFunc != nil {
+		fn, ok := o.costFunc.(func(K, V) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: WithCostFunc function %T does not match the cache's key and value types", o.costFunc))
+		}
+		c.costFunc = fn
+	}
+	if o.store != nil {
+		s, ok := o.store.(Store[K, V])
+		if !ok {
+			panic(fmt.Sprintf("cache: WithStore store %T does not match the cache's key and value types", o.store))
+		}
+		c.backing = s
+		if o.writeBehindInterval > 0 {
+			c.behind = &writeBehind[K, V]{retries: o.writeBehindRetries}
+		}
+	}
+	if o.tinyLFU && c.bounded() {
+		samples := defaultSketchSamples
+		if c.maxEntries > 0 {
+			samples = 10 * c.maxEntries
+		}
+		c.lfu = newTinyLFU[K](samples)
+	}
+	if o.keyIndex {
+		if c.index = newKeyIndex[K](); c.index == nil {
+			panic("cache: WithKeyIndex requires a built-in string or numeric key type")
+		}
+	}
+	return c
+}
+
+// Set stores a value under the key using the cache's default TTL.
+func (c *TypedCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, DefaultExpiration)
+}
+
+// SetWithTTL stores a value under the key that expires after ttl.
+// Use DefaultExpiration to apply the cache's default TTL or NoExpiration to keep the entry forever.
+// If the cache is bounded and full, the least recently used entry is evicted.
+func (c *TypedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.put(key, value, c.deadlineFor(key, ttl), nil, 0)
+}
+
+// SetWith stores a value under the key configured by per-entry options such as WithTTL,
+// WithTags and WithCost. Without WithTTL the default TTL applies.
+func (c *TypedCache[K, V]) SetWith(key K, value V, opts ...SetOption) {
+	var so setOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	c.put(key, value, c.deadlineFor(key, so.ttl), so.tags, so.cost)
+}
+
+// Get returns the value stored under the key. Expired entries are reported as misses.
+// In a bounded cache a hit marks the entry as most recently used.
+func (c *TypedCache[K, V]) Get(key K) (V, bool) {
+	v, ok := c.lookup(key, true)
+	c.countLookup(ok)
+	return v, ok
+}
+
+// lookup is Get without recording the lookup in the statistics. The access is recorded by
+// the admission policy only if record is set.
+func (c *TypedCache[K, V]) lookup(key K, record bool) (V, bool) {
+	if c.bounded() {
+		c.mu.Lock()
+		defer c.unlock()
+	} else {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	if record && c.lfu != nil {
+		c.lfu.record(key)
+	}
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) {
+		var zero V
+		return zero, false
+	}
+	if c.bounded() {
+		c.lru.moveToFront(e)
+	}
+	return c.detach(e.value), true
+}
+
+// Delete removes the key from the cache and reports whether a live entry was removed.
+func (c *TypedCache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	delete(c.failures, key)
+
+	e, ok := c.store[key]
+	if !ok {
+		return c.disk != nil && c.disk.drop(key, c.clock.Now())
+	}
+	if e.expired(c.clock.Now()) {
+		c.removeEntry(e, ReasonExpired)
+		return false
+	}
+	c.removeEntry(e, ReasonDeleted)
+	return true
+}
+
+// Expire sets a new TTL on an existing entry and reports whether the key was found.
+// Use NoExpiration to make the entry persistent.
+func (c *TypedCache[K, V]) Expire(key K, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.find(key)
+	if !ok || e.expired(c.clock.Now()) {
+		return false
+	}
+	e.expiresAt = c.deadline(ttl)
+	c.written(e)
+	return true
+}
+
+// TTL returns the time left until the entry expires, or NoExpiration if it never does.
+// The second result is false if the key is not in the cache.
+func (c *TypedCache[K, V]) TTL(key K) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	var expiresAt time.Time
+	if e, ok := c.store[key]; ok && !e.expired(now) {
+		expiresAt = e.expiresAt
+	} else if de, ok := c.disk.peek(key, now); ok {
+		expiresAt = de.expiresAt
+	} else {
+		return 0, false
+	}
+	if expiresAt.IsZero() {
+		return NoExpiration, true
+	}
+	return expiresAt.Sub(now), true
+}
+
+// Keys returns the keys of all live entries in no particular order.
+func (c *TypedCache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now()
+	keys := make([]K, 0, len(c.store))
+	for key, e := range c.store {
+		if !e.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// DeleteExpired removes every expired entry and returns how many were removed.
+// It is called periodically by the janitor but may also be called directly.
+func (c *TypedCache[K, V]) DeleteExpired() int {
+	c.mu.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	removed := 0
+	for _, e := range c.store {
+		if e.expired(now) && !c.stale(e, now) {
+			c.removeEntry(e, ReasonExpired)
+			removed++
+		}
+	}
+	for key, f := range c.failures {
+		if !now.Before(f.expiresAt) {
+			delete(c.failures, key)
+		}
+	}
+	return removed
+}
+
+// Clear removes every entry from the cache.
+func (c *TypedCache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.unlock()
+	c.clear()
+	c.logClear()
+}
+
+// Len returns the number of stored entries, including expired ones not yet swept.
+func (c *TypedCache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.store)
+}
+
+// Evictions returns how many entries have been evicted to respect the entry limit.
+func (c *TypedCache[K, V]) Evictions() uint64 {
+	return c.stats.evictions.Load()
+}
+
+// Close stops the background janitor and auto-snapshots, if any, and closes the operation log.
+// Changes queued by write-behind are flushed to the store, and when a snapshot path is
+// configured, a final snapshot is written so that no writes since the last one are lost.
+// Change event and channel subscriptions are closed. It is safe to call Close more than once.
+func (c *TypedCache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		if c.janitor != nil {
+			c.janitor.stop()
+		}
+		if c.behind != nil {
+			c.behind.flusher.stop()
+			if err := c.Flush(context.Background()); err != nil {
+				c.errorHandler(err)
+			}
+		}
+		if c.snapshotter != nil {
+			c.snapshotter.stop()
+			c.autoSnapshot()
+		}
+
+		c.mu.Lock()
+		l := c.oplog
+		c.oplog = nil
+		d := c.disk
+		c.disk = nil
+		for sub := range c.subs {
+			close(sub.ch)
+		}
+		c.subs = nil
+		c.mu.Unlock()
+		c.messages.close()
+		if l != nil {
+			if err := l.close(); err != nil {
+				c.errorHandler(fmt.Errorf("cache: close log %s: %w", l.path, err))
+			}
+		}
+		if d != nil {
+			if err := d.clear(); err != nil {
+				c.errorHandler(fmt.Errorf("cache: close disk tier %s: %w", d.dir, err))
+			}
+		}
+	})
+}
+
+// set stores the value with an absolute deadline, keeping the tags of an existing entry.
+// Its cost is computed again. It is used by read-modify-write operations. The caller must hold c.mu.
+func (c *TypedCache[K, V]) set(key K, value V, expiresAt time.Time) {
+	var tags []string
+	if e, ok := c.store[key]; ok {
+		tags = e.tags
+	}
+	c.put(key, value, expiresAt, tags, 0)
+}
+
+// put stores the value with an absolute deadline, the given tags and cost, replacing any
+// existing entry, and evicts entries over the limits. A non-positive cost is computed with
+// entryCost. The caller must hold c.mu.
+func (c *TypedCache[K, V]) put(key K, value V, expiresAt time.Time, tags []string, cost int64) {
+	delete(c.failures, key)
+	c.stats.sets.Add(1)
+	if c.disk != nil {
+		c.disk.drop(key, c.clock.Now())
+	}
+
+	if cost <= 0 {
+		cost = c.entryCost(key, value)
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		// The value can never fit, so drop it instead of emptying the cache for it.
+		if e, ok := c.store[key]; ok {
+			c.dropEntry(e, ReasonEvicted, false)
+		}
+		return
+	}
+
+	c.version++
+	if e, ok := c.store[key]; ok {
+		reason := ReasonReplaced
+		if e.expired(c.clock.Now()) {
+			reason = ReasonExpired
+		}
+		c.notify(reason, key, e.value, e.expiresAt, nil)
+
+		c.stats.cost.Add(cost - e.cost)
+		e.value = value
+		e.expiresAt = expiresAt
+		e.version = c.version
+		e.cost = cost
+		e.loaded = false
+		c.retag(e, tags)
+		c.lru.moveToFront(e)
+		c.written(e)
+		c.evictOverCapacity()
+		return
+	}
+
+	if c.lfu != nil && !c.admit(key, cost) {
+		c.notify(ReasonRejected, key, value, expiresAt, nil)
+		return
+	}
+
+	e := c.link(key, value, expiresAt, tags, cost)
+	c.written(e)
+	c.evictOverCapacity()
+}
+
+// link adds a new entry for a key that is not in the store, with the current version, to the
+// store, the recency list and the indexes. The caller must hold c.mu.
+func (c *TypedCache[K, V]) link(key K, value V, expiresAt time.Time, tags []string, cost int64) *entry[K, V] {
+	e := &entry[K, V]{key: key, value: value, expiresAt: expiresAt, version: c.version, cost: cost}
+	c.store[key] = e
+	c.stats.size.Add(1)
+	c.stats.cost.Add(cost)
+	c.lru.pushFront(e)
+	if c.index != nil {
+		c.index.insert(key)
+	}
+	c.retag(e, tags)
+	return e
+}
+
+// evictOverCapacity evicts least recently used entries until the entry count and the total
+// cost are within their limits. The caller must hold c.mu.
+func (c *TypedCache[K, V]) evictOverCapacity() {
+	for (c.maxEntries > 0 && len(c.store) > c.maxEntries) || (c.maxCost > 0 && c.stats.cost.Load() > c.maxCost) {
+		c.removeEntry(c.lru.back(), ReasonEvicted)
+	}
+}
+
+// entryCost returns the cost of an entry written without an explicit one.
+func (c *TypedCache[K, V]) entryCost(key K, value V) int64 {
+	if c.costFunc == nil {
+		return 1
+	}
+	return max(c.costFunc(key, value), 0)
+}
+
+// bounded reports whether the cache evicts entries, and so has to track their recency.
+func (c *TypedCache[K, V]) bounded() bool {
+	return c.maxEntries > 0 || c.maxCost > 0
+}
+
+// written records a new value or deadline of the entry in the log and publishes it.
+// The caller must hold c.mu.
+func (c *TypedCache[K, V]) written(e *entry[K, V]) {
+	c.logSet(e)
+	c.notify(ReasonSet, e.key, e.value, e.expiresAt, e.tags)
+}
+
+// removeEntry unlinks the entry from the store, the recency list and the indexes, demotes it
+// to the disk tier if it was evicted, logs the removal unless the entry merely expired, and
+// publishes it. The caller must hold c.mu.
+func (c *TypedCache[K, V]) removeEntry(e *entry[K, V], reason Reason) {
+	c.dropEntry(e, reason, reason == ReasonEvicted)
+}
+
+// dropEntry is removeEntry with demotion controlled by demote. Entries that are evicted
+// because their key received a value that can never fit are not demoted, so that the stale
+// value is not promoted again. The caller must hold c.mu.
+func (c *TypedCache[K, V]) dropEntry(e *entry[K, V], reason Reason, demote bool) {
+	delete(c.store, e.key)
+	c.lru.remove(e)
+	if c.index != nil {
+		c.index.remove(e.key)
+	}
+	if demote && c.disk != nil {
+		c.demote(e)
+	}
+	c.retag(e, nil)
+	c.stats.size.Add(-1)
+	c.stats.cost.Add(-e.cost)
+	if reason != ReasonExpired {
+		c.logDelete(e.key)
+	}
+	c.notify(reason, e.key, e.value, e.expiresAt, nil)
+}
+
+// clear drops every entry and remembered loader failure. The caller must hold c.mu.
+func (c *TypedCache[K, V]) clear() {
+	now := c.clock.Now()
+	for _, e := range c.store {
+		reason := ReasonDeleted
+		if e.expired(now) {
+			reason = ReasonExpired
+		}
+		c.notify(reason, e.key, e.value, e.expiresAt, nil)
+	}
+
+	c.store = make(map[K]*entry[K, V])
+	c.stats.size.Store(0)
+	c.stats.cost.Store(0)
+	c.lru = lruList[K, V]{}
+	c.failures = nil
+	c.tags = nil
+	if c.index != nil {
+		c.index.clear()
+	}
+	if c.disk != nil {
+		if err := c.disk.clear(); err != nil {
+			c.errorHandler(fmt.Errorf("cache: clear disk tier: %w", err))
+		}
+	}
+}
+
+// deadlineFor is deadline that also resolves KeepTTL against the current entry for the key.
+// The caller must hold c.mu.
+func (c *TypedCache[K, V]) deadlineFor(key K, ttl time.Duration) time.Time {
+	if ttl == KeepTTL {
+		if e, ok := c.find(key); ok && !e.expired(c.clock.Now()) {
+			return e.expiresAt
+		}
+	}
+	return c.deadline(ttl)
+}
+
+// deadline converts a TTL into an absolute expiration time. A zero time means no expiration.
+func (c *TypedCache[K, V]) deadline(ttl time.Duration) time.Time {
+	if ttl == DefaultExpiration || ttl == KeepTTL {
+		ttl = c.defaultTTL
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return c.clock.Now().Add(ttl)
+}